@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt-functions-sdk/go/fn"
+	"github.com/henderiw-nephio/pkg-examples/allfn/registry"
+	"github.com/henderiw-nephio/pkg-examples/pkg/fnmeta"
+)
+
+const (
+	// fnEnv selects the function in the images built for a single function
+	fnEnv = "KRM_FN"
+
+	listArg  = "list"
+	serveArg = "serve"
+)
+
+// allfn runs one of the registered mutators and validators. The function is
+// selected by the name the binary is invoked with, such that a symlink per
+// function can be used with kpt --exec, by the KRM_FN environment variable of
+// the single function images, or by the first argument:
+//
+//	allfn interface-fn < rl.yaml
+//	allfn interface-fn describe
+//	allfn describe
+//	allfn list
+//	allfn serve -addr :9445
+func main() {
+	args := os.Args[1:]
+	name := filepath.Base(os.Args[0])
+	if _, ok := registry.Functions[name]; !ok {
+		name = os.Getenv(fnEnv)
+	}
+	if _, ok := registry.Functions[name]; !ok && len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+
+	switch name {
+	case listArg:
+		fmt.Println(strings.Join(registry.Names(), "\n"))
+		return
+	case fnmeta.DescribeArg:
+		// all descriptions as a list, ready to be ordered in a pipeline
+		b, err := json.MarshalIndent(registry.Descriptions(), "", "  ")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println(string(b))
+		return
+	case serveArg:
+		if err := serve(args); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	f, ok := registry.Functions[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown function %q, expected one of: %s\n", name, strings.Join(registry.Names(), ", "))
+		os.Exit(2)
+	}
+	if len(args) > 0 && args[0] == fnmeta.DescribeArg {
+		if err := fnmeta.Print(os.Stdout, f.Describe()); err != nil {
+			os.Exit(1)
+		}
+		return
+	}
+	if err := fn.AsMain(f.Run); err != nil {
+		os.Exit(1)
+	}
+}
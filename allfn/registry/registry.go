@@ -0,0 +1,70 @@
+package registry
+
+import (
+	"sort"
+
+	"github.com/GoogleContainerTools/kpt-functions-sdk/go/fn"
+	dnnmutator "github.com/henderiw-nephio/pkg-examples/dnnfn/mutator"
+	interfacemutator "github.com/henderiw-nephio/pkg-examples/interfacefn/mutator"
+	ipammutator "github.com/henderiw-nephio/pkg-examples/ipamfn/mutator"
+	localconfigmutator "github.com/henderiw-nephio/pkg-examples/localconfigfn/mutator"
+	macmutator "github.com/henderiw-nephio/pkg-examples/macfn/mutator"
+	multusmutator "github.com/henderiw-nephio/pkg-examples/multusfn/mutator"
+	nadmutator "github.com/henderiw-nephio/pkg-examples/nadfn/mutator"
+	nfdeploymutator "github.com/henderiw-nephio/pkg-examples/nfdeployfn/mutator"
+	nfdeployvalidatormutator "github.com/henderiw-nephio/pkg-examples/nfdeployvalidatorfn/mutator"
+	orphanmutator "github.com/henderiw-nephio/pkg-examples/orphanfn/mutator"
+	ownershipmutator "github.com/henderiw-nephio/pkg-examples/ownershipfn/mutator"
+	"github.com/henderiw-nephio/pkg-examples/pkg/fnmeta"
+	policymutator "github.com/henderiw-nephio/pkg-examples/policyfn/mutator"
+	rendermutator "github.com/henderiw-nephio/pkg-examples/renderfn/mutator"
+	vlanmutator "github.com/henderiw-nephio/pkg-examples/vlanfn/mutator"
+)
+
+// Function is a mutator or validator registered by name
+type Function struct {
+	Run fn.ResourceListProcessorFunc
+	// Describe returns the for, owned and watched resources of the function,
+	// fnmeta.Functions is generated from them
+	Describe func() fnmeta.Description
+}
+
+// Functions are the registered mutators and validators by the name of their
+// image, the validators are marked as such in their description
+var Functions = map[string]Function{
+	"interface-fn": {Run: interfacemutator.Run, Describe: interfacemutator.Describe},
+	"nad-fn":       {Run: nadmutator.Run, Describe: nadmutator.Describe},
+	"dnn-fn":       {Run: dnnmutator.Run, Describe: dnnmutator.Describe},
+	"nfdeploy-fn":  {Run: nfdeploymutator.Run, Describe: nfdeploymutator.Describe},
+	"ipam-fn":      {Run: ipammutator.Run, Describe: ipammutator.Describe},
+	"vlan-fn":      {Run: vlanmutator.Run, Describe: vlanmutator.Describe},
+	"mac-fn":       {Run: macmutator.Run, Describe: macmutator.Describe},
+	"multus-fn":    {Run: multusmutator.Run, Describe: multusmutator.Describe},
+	"policy-fn":    {Run: policymutator.Run, Describe: policymutator.Describe},
+	"render-fn":    {Run: rendermutator.Run, Describe: rendermutator.Describe},
+	"orphan-fn":    {Run: orphanmutator.Run, Describe: orphanmutator.Describe},
+	// validators
+	"ownership-fn":          {Run: ownershipmutator.Run, Describe: ownershipmutator.Describe},
+	"localconfig-fn":        {Run: localconfigmutator.Run, Describe: localconfigmutator.Describe},
+	"nfdeploy-validator-fn": {Run: nfdeployvalidatormutator.Run, Describe: nfdeployvalidatormutator.Describe},
+}
+
+// Names returns the sorted names of the registered functions
+func Names() []string {
+	names := make([]string, 0, len(Functions))
+	for name := range Functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Descriptions returns the descriptions of the registered functions, sorted
+// by name
+func Descriptions() []fnmeta.Description {
+	descs := []fnmeta.Description{}
+	for _, name := range Names() {
+		descs = append(descs, Functions[name].Describe())
+	}
+	return descs
+}
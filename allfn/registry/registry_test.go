@@ -0,0 +1,48 @@
+package registry
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/henderiw-nephio/pkg-examples/pkg/fnmeta"
+)
+
+// TestDescriptionsMatchFunctions checks that the descriptions in fnmeta, used
+// by the tools that cannot import the fn modules, are generated from the
+// current descriptions of the functions, see go generate in pkg/fnmeta
+func TestDescriptionsMatchFunctions(t *testing.T) {
+	want := map[string]fnmeta.Description{}
+	for _, d := range fnmeta.Functions {
+		want[d.Name] = normalize(d)
+	}
+	got := map[string]fnmeta.Description{}
+	for _, d := range Descriptions() {
+		got[d.Name] = normalize(d)
+	}
+
+	for name, d := range got {
+		w, ok := want[name]
+		if !ok {
+			t.Errorf("function %s is not described in fnmeta.Functions, regenerate them", name)
+			continue
+		}
+		if !reflect.DeepEqual(d, w) {
+			t.Errorf("function %s: fnmeta.Functions has\n%+v\nthe function describes\n%+v, regenerate them", name, w, d)
+		}
+	}
+	for name := range want {
+		if _, ok := got[name]; !ok {
+			t.Errorf("function %s in fnmeta.Functions is not registered with a description", name)
+		}
+	}
+}
+
+// normalize returns a copy of the description without image and with the
+// resources sorted, such that only the resources are compared
+func normalize(d fnmeta.Description) fnmeta.Description {
+	d.Image = ""
+	d.Owns = append([]fnmeta.Resource{}, d.Owns...)
+	d.Watch = append([]fnmeta.Resource{}, d.Watch...)
+	d.Sort()
+	return d
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/GoogleContainerTools/kpt-functions-sdk/go/fn"
+	"github.com/henderiw-nephio/pkg-examples/allfn/registry"
+	"github.com/henderiw-nephio/pkg-examples/pkg/fnserver"
+)
+
+const (
+	readHeaderTimeout = 10 * time.Second
+	idleTimeout       = 2 * time.Minute
+)
+
+// serve exposes the registered functions over http until the server fails
+func serve(args []string) error {
+	fs := flag.NewFlagSet(serveArg, flag.ExitOnError)
+	addr := fs.String("addr", ":9445", "address the server listens on")
+	maxConcurrency := fs.Int("max-concurrency", 4, "number of functions run at the same time")
+	timeout := fs.Duration("timeout", 30*time.Second, "timeout of a request")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	fns := map[string]fn.ResourceListProcessorFunc{}
+	for name, f := range registry.Functions {
+		fns[name] = f.Run
+	}
+	s := fnserver.New(fnserver.Config{
+		Functions:      fns,
+		MaxConcurrency: *maxConcurrency,
+		Timeout:        *timeout,
+	})
+	// the timeouts of the connection keep slow clients from holding it, the
+	// body of a request is read within the timeout of the request
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           s,
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       *timeout,
+		IdleTimeout:       idleTimeout,
+	}
+	log.Printf("serving %d functions on %s", len(fns), *addr)
+	return srv.ListenAndServe()
+}
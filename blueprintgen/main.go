@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/henderiw-nephio/pkg-examples/pkg/blueprint"
+)
+
+// blueprintgen generates a kpt blueprint package from a compact NF profile.
+//
+//	blueprintgen -profile data/upf.profile.yaml -output /tmp/pkg-upf
+//	blueprintgen -profile data/upf.profile.yaml -check data/pkg-upf
+func main() {
+	profile := flag.String("profile", "", "path of the NF profile")
+	output := flag.String("output", "", "directory the blueprint package is written to")
+	check := flag.String("check", "", "directory of a package the generated blueprint is compared with")
+	flag.Parse()
+
+	if *profile == "" || (*output == "" && *check == "") {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	p, err := blueprint.ReadProfile(*profile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	files, err := blueprint.Generate(p)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *output != "" {
+		if err := blueprint.Write(*output, files); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *check != "" {
+		diffs, err := blueprint.Check(*check, files)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, diff := range diffs {
+			fmt.Println(diff)
+		}
+		if len(diffs) > 0 {
+			os.Exit(1)
+		}
+	}
+}
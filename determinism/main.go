@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/henderiw-nephio/pkg-examples/pkg/determinism"
+)
+
+// determinism runs a function several times on a package and once more on
+// its own output and exits non-zero if the items, their field order or the
+// Kptfile conditions differ, reporting the first divergent path. The
+// key=value arguments are passed to the function as function config.
+//
+//	determinism -package data/pkg-upf -fn "go run ./multusfn"
+//	determinism -package data/pkg-upf -fn "allfn dnn-fn" -runs 5
+func main() {
+	pkg := flag.String("package", "", "directory of the package")
+	function := flag.String("fn", "", "function executable and arguments")
+	runs := flag.Int("runs", 3, "number of times the function is run on the package")
+	flag.Parse()
+
+	if *pkg == "" || *function == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	var fc map[string]string
+	for _, arg := range flag.Args() {
+		k, v, ok := strings.Cut(arg, "=")
+		if !ok {
+			log.Fatalf("invalid function config %q, expected key=value", arg)
+		}
+		if fc == nil {
+			fc = map[string]string{}
+		}
+		fc[k] = v
+	}
+
+	report, err := determinism.Check(&determinism.Config{
+		Package:        *pkg,
+		Function:       strings.Fields(*function),
+		FunctionConfig: fc,
+		Runs:           *runs,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Print(report.String())
+	if !report.Deterministic() {
+		os.Exit(1)
+	}
+}
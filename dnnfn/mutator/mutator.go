@@ -5,7 +5,11 @@ import (
 	"reflect"
 
 	"github.com/GoogleContainerTools/kpt-functions-sdk/go/fn"
+	"github.com/henderiw-nephio/pkg-examples/pkg/children"
+	"github.com/henderiw-nephio/pkg-examples/pkg/fnmeta"
 	ko "github.com/henderiw-nephio/pkg-examples/pkg/kubeobject"
+	"github.com/henderiw-nephio/pkg-examples/pkg/localconfig"
+	"github.com/henderiw-nephio/pkg-examples/pkg/pkgcontext"
 	nephioreqv1alpha1 "github.com/nephio-project/api/nf_requirements/v1alpha1"
 	infrav1alpha1 "github.com/nephio-project/nephio-controller-poc/apis/infra/v1alpha1"
 	"github.com/nephio-project/nephio/krm-functions/lib/condkptsdk"
@@ -15,43 +19,57 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const fnName = "dnn-fn"
+
 type mutatorCtx struct {
 	sdk      condkptsdk.KptCondSDK
+	pkgCtx   *pkgcontext.PackageContext
 	siteCode string
 }
 
 func Run(rl *fn.ResourceList) (bool, error) {
-	m := mutatorCtx{}
+	m := mutatorCtx{
+		pkgCtx: pkgcontext.New(rl.Items),
+	}
 	var err error
-	m.sdk, err = condkptsdk.New(
-		rl,
-		&condkptsdk.Config{
-			For: corev1.ObjectReference{
-				APIVersion: nephioreqv1alpha1.GroupVersion.Identifier(),
-				Kind:       nephioreqv1alpha1.DataNetworkKind,
-			},
-			Owns: map[corev1.ObjectReference]condkptsdk.ResourceKind{
-				{
-					APIVersion: ipamv1alpha1.GroupVersion.Identifier(),
-					Kind:       ipamv1alpha1.IPAllocationKind,
-				}: condkptsdk.ChildRemote,
-			},
-			Watch: map[corev1.ObjectReference]condkptsdk.WatchCallbackFn{
-				{
-					APIVersion: infrav1alpha1.GroupVersion.Identifier(),
-					Kind:       reflect.TypeOf(infrav1alpha1.ClusterContext{}).Name(),
-				}: m.ClusterContextCallbackFn,
-			},
-			PopulateOwnResourcesFn: m.desiredOwnedResourceList,
-			GenerateResourceFn:     m.updateDnnResource,
-		},
-	)
+	m.sdk, err = condkptsdk.New(rl, m.config())
 	if err != nil {
 		rl.Results = append(rl.Results, fn.ErrorConfigObjectResult(err, nil))
 	}
 	return m.sdk.Run()
 }
 
+// config returns the condkptsdk config of the function
+func (r *mutatorCtx) config() *condkptsdk.Config {
+	return &condkptsdk.Config{
+		For: corev1.ObjectReference{
+			APIVersion: nephioreqv1alpha1.GroupVersion.Identifier(),
+			Kind:       nephioreqv1alpha1.DataNetworkKind,
+		},
+		Owns: map[corev1.ObjectReference]condkptsdk.ResourceKind{
+			{
+				APIVersion: ipamv1alpha1.GroupVersion.Identifier(),
+				Kind:       ipamv1alpha1.IPAllocationKind,
+			}: condkptsdk.ChildRemote,
+		},
+		Watch: map[corev1.ObjectReference]condkptsdk.WatchCallbackFn{
+			{
+				APIVersion: infrav1alpha1.GroupVersion.Identifier(),
+				Kind:       reflect.TypeOf(infrav1alpha1.ClusterContext{}).Name(),
+			}: r.ClusterContextCallbackFn,
+		},
+		PopulateOwnResourcesFn: r.desiredOwnedResourceList,
+		GenerateResourceFn:     r.updateDnnResource,
+	}
+}
+
+// Describe returns the description of the for, owned and watched resources of
+// the function
+func Describe() fnmeta.Description {
+	cfg := (&mutatorCtx{}).config()
+	return fnmeta.NewDescription(fnName, cfg.For, cfg.Owns, cfg.Watch)
+}
+
 // ClusterContextCallbackFn provides a callback for the cluster context
 // resources in the resourceList
 func (r *mutatorCtx) ClusterContextCallbackFn(o *fn.KubeObject) error {
@@ -90,9 +108,7 @@ func (r *mutatorCtx) desiredOwnedResourceList(o *fn.KubeObject) (fn.KubeObjects,
 
 	for _, pool := range dnn.Spec.Pools {
 		alloc := ipamv1alpha1.BuildIPAllocation(
-			metav1.ObjectMeta{
-				Name: fmt.Sprintf("%s-%s", o.GetName(), pool.Name),
-			},
+			r.pkgCtx.BuildObjectMeta(o, children.PoolAllocationName(r.pkgCtx.ChildName(o.GetName()), pool.Name), fnName),
 			ipamv1alpha1.IPAllocationSpec{
 				Kind:            ipamv1alpha1.PrefixKindPool,
 				NetworkInstance: dnn.Spec.NetworkInstance,
@@ -111,6 +127,10 @@ func (r *mutatorCtx) desiredOwnedResourceList(o *fn.KubeObject) (fn.KubeObjects,
 		if err != nil {
 			return nil, err
 		}
+		// pool allocations only live in the package
+		if err := localconfig.Set(o, true); err != nil {
+			return nil, err
+		}
 
 		resources = append(resources, o)
 	}
@@ -130,6 +150,12 @@ func (r *mutatorCtx) updateDnnResource(forObj *fn.KubeObject, objs fn.KubeObject
 	if err != nil {
 		return nil, err
 	}
+	// the pool status is named after the pool, not after its ip allocation
+	// which is prefixed with the package name
+	poolNames := map[string]string{}
+	for _, pool := range dnn.Spec.Pools {
+		poolNames[children.PoolAllocationName(r.pkgCtx.ChildName(forObj.GetName()), pool.Name)] = pool.Name
+	}
 	ipallocs := objs.Where(fn.IsGroupVersionKind(ipamv1alpha1.IPAllocationGroupVersionKind))
 	for _, ipalloc := range ipallocs {
 		alloc, err := ko.NewFromKubeObject[*ipamv1alpha1.IPAllocation](ipalloc)
@@ -140,8 +166,11 @@ func (r *mutatorCtx) updateDnnResource(forObj *fn.KubeObject, objs fn.KubeObject
 		if err != nil {
 			return nil, err
 		}
-		// todo update pool status
-		dnn.Status.Pools = append(dnn.Status.Pools, nephioreqv1alpha1.PoolStatus{Name: alloc.GetName(), IPAllocation: allocGoStruct.Status})
+		name, ok := poolNames[alloc.GetName()]
+		if !ok {
+			continue
+		}
+		dnn.Status.Pools = append(dnn.Status.Pools, nephioreqv1alpha1.PoolStatus{Name: name, IPAllocation: allocGoStruct.Status})
 
 	}
 	err = dnnKOE.SetFromTypedObject(dnn)
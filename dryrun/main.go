@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/henderiw-nephio/pkg-examples/pkg/dryrun"
+)
+
+type functions [][]string
+
+func (r *functions) String() string {
+	s := make([]string, 0, len(*r))
+	for _, f := range *r {
+		s = append(s, strings.Join(f, " "))
+	}
+	return strings.Join(s, ", ")
+}
+
+func (r *functions) Set(s string) error {
+	*r = append(*r, strings.Fields(s))
+	return nil
+}
+
+// dryrun runs a function chain on a package without writing it and prints a
+// unified diff per file with a summary of the created, updated and deleted
+// objects and the changed Kptfile conditions. The key=value arguments are
+// passed to the functions as function config, like with kpt fn eval.
+//
+//	dryrun -package data/pkg-upf -fn "go run ./multusfn"
+//	dryrun -package data/pkg-upf -fn "allfn nad-fn" ipamType=whereabouts
+func main() {
+	var fns functions
+	pkg := flag.String("package", "", "directory of the package")
+	flag.Var(&fns, "fn", "function executable and arguments, can be repeated to build the chain")
+	flag.Parse()
+
+	if *pkg == "" || len(fns) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	var fc map[string]string
+	for _, arg := range flag.Args() {
+		k, v, ok := strings.Cut(arg, "=")
+		if !ok {
+			log.Fatalf("invalid function config %q, expected key=value", arg)
+		}
+		if fc == nil {
+			fc = map[string]string{}
+		}
+		fc[k] = v
+	}
+
+	report, err := dryrun.Run(&dryrun.Config{
+		Package:        *pkg,
+		Functions:      fns,
+		FunctionConfig: fc,
+	})
+	// the report of a failed chain shows the results of the failed function
+	if report != nil {
+		fmt.Print(report.String())
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
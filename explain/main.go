@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/henderiw-nephio/pkg-examples/pkg/explain"
+	"github.com/henderiw-nephio/pkg-examples/pkg/fnmeta"
+	"github.com/henderiw-nephio/pkg-examples/pkg/ownergraph"
+)
+
+// explain prints why a resource exists in a package and what it is waiting
+// for. The resource is referenced by its Kptfile condition type or by
+// <kind>/<name>.
+//
+//	explain -package data/pkg-upf NetworkAttachmentDefinition/n4
+//	explain -package data/pkg-upf ipam.alloc.nephio.org/v1alpha1.IPAllocation.n6
+func main() {
+	pkg := flag.String("package", "", "directory of the package")
+	flag.Parse()
+
+	if *pkg == "" || flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	p, err := ownergraph.ReadPackage(*pkg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	g, err := ownergraph.Build(p)
+	if err != nil {
+		log.Fatal(err)
+	}
+	node, err := explain.Resolve(g, flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Print(explain.Explain(g, node, fnmeta.Functions).String())
+}
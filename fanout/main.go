@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/henderiw-nephio/pkg-examples/pkg/fanout"
+)
+
+type functions [][]string
+
+func (r *functions) String() string {
+	s := make([]string, 0, len(*r))
+	for _, f := range *r {
+		s = append(s, strings.Join(f, " "))
+	}
+	return strings.Join(s, ", ")
+}
+
+func (r *functions) Set(s string) error {
+	*r = append(*r, strings.Fields(s))
+	return nil
+}
+
+// fanout specializes a blueprint package for every ClusterContext in a
+// directory by injecting the ClusterContext and running the function chain.
+//
+//	fanout -blueprint data/pkg-upf -clusters data/clusters -output /tmp/upf \
+//	  -fn "go run ./macfn" -fn "go run ./multusfn"
+func main() {
+	var fns functions
+	blueprint := flag.String("blueprint", "", "directory of the blueprint package")
+	clusters := flag.String("clusters", "", "directory with the ClusterContext objects")
+	output := flag.String("output", "", "directory the specialized packages are written to")
+	flag.Var(&fns, "fn", "function executable and arguments, can be repeated to build the chain")
+	flag.Parse()
+
+	if *blueprint == "" || *clusters == "" || *output == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	clusterContexts, err := fanout.ReadClusterContexts(*clusters)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(clusterContexts) == 0 {
+		log.Fatalf("no ClusterContext found in %s", *clusters)
+	}
+	summary, err := fanout.Run(&fanout.Config{
+		Blueprint: *blueprint,
+		Output:    *output,
+		Functions: fns,
+	}, clusterContexts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Print(summary.String())
+	if summary.HasErrors() {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/henderiw-nephio/pkg-examples/pkg/fnserver"
+)
+
+// fnclient runs a function on a function server with the ResourceList read
+// from stdin and writes the resulting ResourceList to stdout, such that it can
+// be used in place of the function binary.
+//
+//	fnclient -server http://localhost:9445 -list
+//	fnclient -server http://localhost:9445 -fn interface-fn < rl.yaml
+func main() {
+	server := flag.String("server", "http://localhost:9445", "url of the function server")
+	name := flag.String("fn", "", "name of the function to run")
+	list := flag.Bool("list", false, "list the functions of the server")
+	timeout := flag.Duration("timeout", time.Minute, "timeout of the request")
+	flag.Parse()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	c := fnserver.NewClient(*server)
+
+	if *list {
+		names, err := c.List(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(strings.Join(names, "\n"))
+		return
+	}
+	if *name == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	in, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		log.Fatal(err)
+	}
+	out, err := c.Run(ctx, *name, in)
+	if out != nil {
+		os.Stdout.Write(out)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
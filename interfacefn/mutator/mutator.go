@@ -5,7 +5,13 @@ import (
 	"reflect"
 
 	"github.com/GoogleContainerTools/kpt-functions-sdk/go/fn"
+	"github.com/henderiw-nephio/pkg-examples/pkg/children"
+	"github.com/henderiw-nephio/pkg-examples/pkg/fnmeta"
 	ko "github.com/henderiw-nephio/pkg-examples/pkg/kubeobject"
+	"github.com/henderiw-nephio/pkg-examples/pkg/localconfig"
+	"github.com/henderiw-nephio/pkg-examples/pkg/macalloc"
+	"github.com/henderiw-nephio/pkg-examples/pkg/pkgcontext"
+	"github.com/henderiw-nephio/pkg-examples/pkg/replicas"
 	nadv1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
 	nephioreqv1alpha1 "github.com/nephio-project/api/nf_requirements/v1alpha1"
 	infrav1alpha1 "github.com/nephio-project/nephio-controller-poc/apis/infra/v1alpha1"
@@ -19,49 +25,30 @@ import (
 	vlanlibv1alpha1 "github.com/nephio-project/nephio/krm-functions/lib/vlanalloc/v1alpha1"
 )
 
-const defaultPODNetwork = "defaultPODNetwork"
+const (
+	fnName = "interface-fn"
+)
 
 type itfceFn struct {
 	sdk             condkptsdk.KptCondSDK
+	pkgCtx          *pkgcontext.PackageContext
+	capacity        *fn.KubeObject
 	siteCode        string
 	masterInterface string
 	cniType         string
 }
 
 func Run(rl *fn.ResourceList) (bool, error) {
-	myFn := itfceFn{}
+	myFn := itfceFn{
+		pkgCtx: pkgcontext.New(rl.Items),
+	}
+	// the capacity is optional and only provides the default replicas of the
+	// interfaces, hence it is not watched to avoid blocking the readiness
+	for _, o := range rl.Items.Where(fn.IsGroupVersionKind(nephioreqv1alpha1.CapacityGroupVersionKind)) {
+		myFn.capacity = o
+	}
 	var err error
-	myFn.sdk, err = condkptsdk.New(
-		rl,
-		&condkptsdk.Config{
-			For: corev1.ObjectReference{
-				APIVersion: nephioreqv1alpha1.GroupVersion.Identifier(),
-				Kind:       nephioreqv1alpha1.InterfaceKind,
-			},
-			Owns: map[corev1.ObjectReference]condkptsdk.ResourceKind{
-				{
-					APIVersion: nadv1.SchemeGroupVersion.Identifier(),
-					Kind:       reflect.TypeOf(nadv1.NetworkAttachmentDefinition{}).Name(),
-				}: condkptsdk.ChildRemoteCondition,
-				{
-					APIVersion: ipamv1alpha1.GroupVersion.Identifier(),
-					Kind:       ipamv1alpha1.IPAllocationKind,
-				}: condkptsdk.ChildRemote,
-				{
-					APIVersion: vlanv1alpha1.GroupVersion.Identifier(),
-					Kind:       vlanv1alpha1.VLANAllocationKind,
-				}: condkptsdk.ChildRemote,
-			},
-			Watch: map[corev1.ObjectReference]condkptsdk.WatchCallbackFn{
-				{
-					APIVersion: infrav1alpha1.GroupVersion.Identifier(),
-					Kind:       reflect.TypeOf(infrav1alpha1.ClusterContext{}).Name(),
-				}: myFn.ClusterContextCallbackFn,
-			},
-			PopulateOwnResourcesFn: myFn.desiredOwnedResourceList,
-			GenerateResourceFn:     myFn.updateItfceResource,
-		},
-	)
+	myFn.sdk, err = condkptsdk.New(rl, myFn.config())
 	if err != nil {
 		rl.Results.ErrorE(err)
 		return false, nil
@@ -69,6 +56,45 @@ func Run(rl *fn.ResourceList) (bool, error) {
 	return myFn.sdk.Run()
 }
 
+// config returns the condkptsdk config of the function
+func (r *itfceFn) config() *condkptsdk.Config {
+	return &condkptsdk.Config{
+		For: corev1.ObjectReference{
+			APIVersion: nephioreqv1alpha1.GroupVersion.Identifier(),
+			Kind:       nephioreqv1alpha1.InterfaceKind,
+		},
+		Owns: map[corev1.ObjectReference]condkptsdk.ResourceKind{
+			{
+				APIVersion: nadv1.SchemeGroupVersion.Identifier(),
+				Kind:       reflect.TypeOf(nadv1.NetworkAttachmentDefinition{}).Name(),
+			}: condkptsdk.ChildRemoteCondition,
+			{
+				APIVersion: ipamv1alpha1.GroupVersion.Identifier(),
+				Kind:       ipamv1alpha1.IPAllocationKind,
+			}: condkptsdk.ChildRemote,
+			{
+				APIVersion: vlanv1alpha1.GroupVersion.Identifier(),
+				Kind:       vlanv1alpha1.VLANAllocationKind,
+			}: condkptsdk.ChildRemote,
+		},
+		Watch: map[corev1.ObjectReference]condkptsdk.WatchCallbackFn{
+			{
+				APIVersion: infrav1alpha1.GroupVersion.Identifier(),
+				Kind:       reflect.TypeOf(infrav1alpha1.ClusterContext{}).Name(),
+			}: r.ClusterContextCallbackFn,
+		},
+		PopulateOwnResourcesFn: r.desiredOwnedResourceList,
+		GenerateResourceFn:     r.updateItfceResource,
+	}
+}
+
+// Describe returns the description of the for, owned and watched resources of
+// the function
+func Describe() fnmeta.Description {
+	cfg := (&itfceFn{}).config()
+	return fnmeta.NewDescription(fnName, cfg.For, cfg.Owns, cfg.Watch)
+}
+
 // ClusterContextCallbackFn provides a callback for the cluster context
 // resources in the resourceList
 func (r *itfceFn) ClusterContextCallbackFn(o *fn.KubeObject) error {
@@ -116,48 +142,53 @@ func (r *itfceFn) desiredOwnedResourceList(o *fn.KubeObject) (fn.KubeObjects, er
 		return nil, err
 	}
 
-	// Nothing to be done in case the interface is attached to
-	// the default pod network since this is all handled in the
-	// k8s cluster via the CNI.
-	if itfce.Spec.NetworkInstance.Name == defaultPODNetwork {
-		return fn.KubeObjects{}, nil
+	// the names of the children are shared with the ownership checks: no
+	// children for the default pod network, which is handled by the k8s
+	// cluster CNI, an IP allocation per replica such that every replica gets
+	// a distinct address, a VLAN allocation for a vlan attachment and a nad
+	// per replica unless the replicas share the nad through its ipam
+	names, err := children.Interface(r.pkgCtx, o, r.capacity)
+	if err != nil {
+		return nil, err
 	}
-
-	// meta is the generic object meta attached to all derived child objects
-	meta := metav1.ObjectMeta{
-		Name: o.GetName(),
+	if len(names) == 0 {
+		return resources, nil
 	}
+
 	// When the CNIType is not set this is a loopback interface
+	prefixKind := ipamv1alpha1.PrefixKindLoopback
 	if itfce.Spec.CNIType != "" {
 		if itfce.Spec.CNIType != nephioreqv1alpha1.CNIType(r.cniType) {
 			return nil, fmt.Errorf("cluster cniType not supported: cluster cniType: %s, interface cniType: %s", r.cniType, itfce.Spec.CNIType)
 		}
-		// add IP allocation of type network
-		o, err := r.getIPAllocation(meta, *itfce.Spec.NetworkInstance, ipamv1alpha1.PrefixKindNetwork)
+		prefixKind = ipamv1alpha1.PrefixKindNetwork
+		fn.Logf("itfce attachementType: %s\n", itfce.Spec.AttachmentType)
+	}
+
+	// meta is the generic object meta attached to all derived child objects
+	meta := r.pkgCtx.BuildObjectMeta(o, o.GetName(), fnName)
+	for _, name := range names[children.IPAllocationKind] {
+		allocMeta := *meta.DeepCopy()
+		allocMeta.Name = name
+		o, err := r.getIPAllocation(allocMeta, *itfce.Spec.NetworkInstance, prefixKind)
 		if err != nil {
 			return nil, err
 		}
 		resources = append(resources, o)
-
-		fn.Logf("itfce attachementType: %s\n", itfce.Spec.AttachmentType)
-		if itfce.Spec.AttachmentType == nephioreqv1alpha1.AttachmentTypeVLAN {
-			// add VLAN allocation
-			o, err := r.getVLANAllocation(meta)
-			if err != nil {
-				return nil, err
-			}
-			resources = append(resources, o)
-		}
-
-		// allocate nad
-		o, err = r.getNAD(meta)
+	}
+	for _, name := range names[children.VLANAllocationKind] {
+		allocMeta := *meta.DeepCopy()
+		allocMeta.Name = name
+		o, err := r.getVLANAllocation(allocMeta)
 		if err != nil {
 			return nil, err
 		}
 		resources = append(resources, o)
-	} else {
-		// add IP allocation of type loopback
-		o, err := r.getIPAllocation(meta, *itfce.Spec.NetworkInstance, ipamv1alpha1.PrefixKindLoopback)
+	}
+	for _, name := range names[children.NADKind] {
+		nadMeta := *meta.DeepCopy()
+		nadMeta.Name = name
+		o, err := r.getNAD(nadMeta)
 		if err != nil {
 			return nil, err
 		}
@@ -179,23 +210,27 @@ func (r *itfceFn) updateItfceResource(forObj *fn.KubeObject, objs fn.KubeObjects
 		return nil, err
 	}
 
-	ipallocs := objs.Where(fn.IsGroupVersionKind(ipamv1alpha1.IPAllocationGroupVersionKind))
+	// the allocations are sorted by replica index, the first one is reflected
+	// in the interface status for backward compatibility
+	ipallocStatus := []ipamv1alpha1.IPAllocationStatus{}
+	ipallocs := replicas.Filter(objs.Where(fn.IsGroupVersionKind(ipamv1alpha1.IPAllocationGroupVersionKind)), forObj, r.pkgCtx.ChildName(forObj.GetName()))
 	for _, ipalloc := range ipallocs {
-		if ipalloc.GetName() == forObj.GetName() {
-			alloc, err := ko.NewFromKubeObject[*ipamv1alpha1.IPAllocation](ipalloc)
-			if err != nil {
-				return nil, err
-			}
-			allocGoStruct, err := alloc.GetGoStruct()
-			if err != nil {
-				return nil, err
-			}
-			itfce.Status.IPAllocationStatus = &allocGoStruct.Status
+		alloc, err := ko.NewFromKubeObject[*ipamv1alpha1.IPAllocation](ipalloc)
+		if err != nil {
+			return nil, err
 		}
+		allocGoStruct, err := alloc.GetGoStruct()
+		if err != nil {
+			return nil, err
+		}
+		ipallocStatus = append(ipallocStatus, allocGoStruct.Status)
+	}
+	if len(ipallocStatus) > 0 {
+		itfce.Status.IPAllocationStatus = &ipallocStatus[0]
 	}
 	vlanallocs := objs.Where(fn.IsGroupVersionKind(vlanv1alpha1.VLANAllocationGroupVersionKind))
 	for _, vlanalloc := range vlanallocs {
-		if vlanalloc.GetName() == forObj.GetName() {
+		if vlanalloc.GetName() == r.pkgCtx.ChildName(forObj.GetName()) {
 			alloc, err := vlanlibv1alpha1.NewFromKubeObject(vlanalloc)
 			if err != nil {
 				return nil, err
@@ -211,9 +246,22 @@ func (r *itfceFn) updateItfceResource(forObj *fn.KubeObject, objs fn.KubeObjects
 			itfce.Status.VLANAllocationStatus = &allocGoStruct.Status
 		}
 	}
-	// set the status
-	err = itfceKOE.SetFromTypedObject(itfce)
-	return &itfceKOE.KubeObject, err
+	// set the status, the mac addresses allocated by mac-fn are not part of
+	// the typed struct and are carried over
+	macs := macalloc.Get(forObj)
+	if err := itfceKOE.SetFromTypedObject(itfce); err != nil {
+		return nil, err
+	}
+	if err := macalloc.Set(&itfceKOE.KubeObject, macs); err != nil {
+		return nil, err
+	}
+	// list the allocation status of all replicas
+	if len(ipallocStatus) > 1 {
+		if err := itfceKOE.SetNestedField(ipallocStatus, replicas.StatusField...); err != nil {
+			return nil, err
+		}
+	}
+	return &itfceKOE.KubeObject, nil
 }
 
 func (r *itfceFn) getVLANAllocation(meta metav1.ObjectMeta) (*fn.KubeObject, error) {
@@ -226,8 +274,7 @@ func (r *itfceFn) getVLANAllocation(meta metav1.ObjectMeta) (*fn.KubeObject, err
 		},
 		vlanv1alpha1.VLANAllocationStatus{},
 	)
-
-	return fn.NewFromTypedObject(alloc)
+	return newLocalObject(alloc, true)
 }
 
 func (r *itfceFn) getIPAllocation(meta metav1.ObjectMeta, ni corev1.ObjectReference, kind ipamv1alpha1.PrefixKind) (*fn.KubeObject, error) {
@@ -246,7 +293,7 @@ func (r *itfceFn) getIPAllocation(meta metav1.ObjectMeta, ni corev1.ObjectRefere
 		},
 		ipamv1alpha1.IPAllocationStatus{},
 	)
-	return fn.NewFromTypedObject(alloc)
+	return newLocalObject(alloc, true)
 }
 
 func (r *itfceFn) getNAD(meta metav1.ObjectMeta) (*fn.KubeObject, error) {
@@ -254,7 +301,21 @@ func (r *itfceFn) getNAD(meta metav1.ObjectMeta) (*fn.KubeObject, error) {
 		meta,
 		nadv1.NetworkAttachmentDefinitionSpec{},
 	)
-	return fn.NewFromTypedObject(nad)
+	return newLocalObject(nad, false)
+}
+
+// newLocalObject returns a KubeObject from the typed object with the
+// local-config annotation set: allocations only live in the package while
+// the nad is applied to the cluster
+func newLocalObject(x any, local bool) (*fn.KubeObject, error) {
+	o, err := fn.NewFromTypedObject(x)
+	if err != nil {
+		return nil, err
+	}
+	if err := localconfig.Set(o, local); err != nil {
+		return nil, err
+	}
+	return o, nil
 }
 
 func BuildNetworkAttachmentDefinition(meta metav1.ObjectMeta, spec nadv1.NetworkAttachmentDefinitionSpec) *nadv1.NetworkAttachmentDefinition {
@@ -0,0 +1,88 @@
+package mutator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/GoogleContainerTools/kpt-functions-sdk/go/fn"
+	"github.com/henderiw-nephio/pkg-examples/pkg/fnmeta"
+	ko "github.com/henderiw-nephio/pkg-examples/pkg/kubeobject"
+	"github.com/nephio-project/nephio/krm-functions/lib/condkptsdk"
+	ipamv1alpha1 "github.com/nokia/k8s-ipam/apis/alloc/ipam/v1alpha1"
+	"github.com/nokia/k8s-ipam/pkg/proxy/clientproxy"
+	"github.com/nokia/k8s-ipam/pkg/proxy/clientproxy/ipam"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const fnName = "ipam-fn"
+
+// Run allocates the ip allocations in the package using the ipam backend
+func Run(rl *fn.ResourceList) (bool, error) {
+	r := &FnR{
+		IpamClientProxy: ipam.NewMock(),
+	}
+	return r.Run(rl)
+}
+
+type FnR struct {
+	IpamClientProxy clientproxy.Proxy[*ipamv1alpha1.NetworkInstance, *ipamv1alpha1.IPAllocation]
+}
+
+func (r *FnR) Run(rl *fn.ResourceList) (bool, error) {
+	sdk, err := condkptsdk.New(rl, r.config())
+	if err != nil {
+		rl.Results.ErrorE(err)
+		return false, nil
+	}
+	return sdk.Run()
+}
+
+// config returns the condkptsdk config of the function
+func (r *FnR) config() *condkptsdk.Config {
+	return &condkptsdk.Config{
+		For: corev1.ObjectReference{
+			APIVersion: ipamv1alpha1.GroupVersion.Identifier(),
+			Kind:       ipamv1alpha1.IPAllocationKind,
+		},
+		PopulateOwnResourcesFn: nil,
+		GenerateResourceFn:     r.updateIPAllocationResource,
+	}
+}
+
+// Describe returns the description of the for, owned and watched resources of
+// the function
+func Describe() fnmeta.Description {
+	cfg := (&FnR{}).config()
+	return fnmeta.NewDescription(fnName, cfg.For, cfg.Owns, cfg.Watch)
+}
+
+func (r *FnR) updateIPAllocationResource(forObj *fn.KubeObject, objs fn.KubeObjects) (*fn.KubeObject, error) {
+	if forObj == nil {
+		return nil, fmt.Errorf("expected a for object but got nil")
+	}
+	fn.Logf("ipalloc: %v\n", forObj)
+	allocKOE, err := ko.NewFromKubeObject[*ipamv1alpha1.IPAllocation](forObj)
+	if err != nil {
+		return nil, err
+	}
+	alloc, err := allocKOE.GetGoStruct()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.IpamClientProxy.Allocate(context.Background(), alloc, nil)
+	if err != nil {
+		return nil, err
+	}
+	alloc.Status = resp.Status
+
+	if alloc.Status.Prefix != nil {
+		fn.Logf("ipalloc resp prefix: %v\n", *resp.Status.Prefix)
+	}
+	if alloc.Status.Gateway != nil {
+		fn.Logf("ipalloc resp gateway: %v\n", *resp.Status.Gateway)
+	}
+	// set the status
+	//err = allocKOE.SetStatus(resp.Status)
+	err = allocKOE.SetFromTypedObject(resp)
+	return &allocKOE.KubeObject, err
+}
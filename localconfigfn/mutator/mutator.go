@@ -0,0 +1,28 @@
+package mutator
+
+import (
+	"github.com/GoogleContainerTools/kpt-functions-sdk/go/fn"
+	"github.com/henderiw-nephio/pkg-examples/pkg/fnmeta"
+	"github.com/henderiw-nephio/pkg-examples/pkg/localconfig"
+)
+
+const fnName = "localconfig-fn"
+
+// Describe returns the description of the function, it validates the whole
+// package and has no for-object
+func Describe() fnmeta.Description {
+	return fnmeta.Description{Name: fnName, Validator: true}
+}
+
+// Run validates that requirement objects in the package are marked as
+// local-config and that the objects to be applied are not.
+func Run(rl *fn.ResourceList) (bool, error) {
+	results := localconfig.Validate(rl.Items)
+	rl.Results = append(rl.Results, results...)
+	for _, result := range results {
+		if result.Severity == fn.Error {
+			return false, nil
+		}
+	}
+	return true, nil
+}
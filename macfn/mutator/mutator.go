@@ -0,0 +1,220 @@
+package mutator
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/GoogleContainerTools/kpt-functions-sdk/go/fn"
+	"github.com/henderiw-nephio/pkg-examples/pkg/dependson"
+	"github.com/henderiw-nephio/pkg-examples/pkg/fnmeta"
+	"github.com/henderiw-nephio/pkg-examples/pkg/macalloc"
+	"github.com/henderiw-nephio/pkg-examples/pkg/multus"
+	nadlibv1 "github.com/henderiw-nephio/pkg-examples/pkg/nad/v1"
+	"github.com/henderiw-nephio/pkg-examples/pkg/pkgcontext"
+	"github.com/henderiw-nephio/pkg-examples/pkg/replicas"
+	nadv1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	fnName            = "mac-fn"
+	defaultPODNetwork = "defaultPODNetwork"
+	// prefixConfigKey is the key in the function config that sets the prefix
+	// of the mac addresses, e.g. 02:1a:2b, instead of deriving it from the
+	// site code
+	prefixConfigKey = "prefix"
+)
+
+var (
+	interfaceGVK      = schema.GroupVersionKind{Group: "req.nephio.org", Version: "v1alpha1", Kind: "Interface"}
+	clusterContextGVK = schema.GroupVersionKind{Group: "infra.nephio.org", Version: "v1alpha1", Kind: "ClusterContext"}
+	nadGVK            = nadv1.SchemeGroupVersion.WithKind(reflect.TypeOf(nadv1.NetworkAttachmentDefinition{}).Name())
+)
+
+// Describe returns the description of the function. The nads pinning a mac
+// address are not watched as nad-fn consumes the allocated mac addresses.
+func Describe() fnmeta.Description {
+	d := fnmeta.Description{
+		Name:  fnName,
+		For:   fnmeta.NewResource(interfaceGVK),
+		Watch: []fnmeta.Resource{fnmeta.NewResource(clusterContextGVK)},
+	}
+	d.Sort()
+	return d
+}
+
+// Run allocates a mac address from the pool of the site for every replica of
+// the interfaces attached through a nad. The allocations are persisted in a
+// local ConfigMap in the package such that the mac addresses are stable
+// across runs. The mac addresses are written to the status of the interface
+// by replica index, see macalloc.StatusField, from which nad-fn, multus-fn and
+// render-fn read them. The prefix of the pool is derived from the site code
+// unless it is set through the prefix key of the function config.
+func Run(rl *fn.ResourceList) (bool, error) {
+	pool, err := getPool(rl)
+	if err != nil {
+		rl.Results.ErrorE(err)
+		return false, nil
+	}
+	backend, err := macalloc.NewConfigMapBackend(rl.Items)
+	if err != nil {
+		rl.Results.ErrorE(err)
+		return false, nil
+	}
+
+	pkgCtx := pkgcontext.New(rl.Items)
+	keys := map[string]struct{}{}
+	// nadMacs holds the mac address pinned through a nad by the name of the
+	// nad
+	nadMacs := map[string]string{}
+	for _, itfce := range rl.Items.Where(fn.IsGroupVersionKind(interfaceGVK)) {
+		nrReplicas, err := getMacReplicas(itfce)
+		if err != nil {
+			rl.Results = append(rl.Results, fn.ErrorConfigObjectResult(err, itfce))
+			return false, nil
+		}
+		macs := make([]string, 0, nrReplicas)
+		for i := 0; i < nrReplicas; i++ {
+			key := macalloc.Key(itfce.GetName(), i)
+			mac, err := backend.Allocate(pool, key)
+			if err != nil {
+				rl.Results = append(rl.Results, fn.ErrorConfigObjectResult(err, itfce))
+				return false, nil
+			}
+			keys[key] = struct{}{}
+			macs = append(macs, mac)
+		}
+		if err := macalloc.Set(itfce, macs); err != nil {
+			rl.Results = append(rl.Results, fn.ErrorConfigObjectResult(err, itfce))
+			return false, nil
+		}
+		// the mac address of a replica is pinned through the nad of the
+		// replica, a nad shared by several replicas cannot pin one
+		name := pkgCtx.ChildName(itfce.GetName())
+		nads := replicas.Filter(rl.Items.Where(fn.IsGroupVersionKind(nadGVK)), itfce, name)
+		if len(nads) > 1 || nrReplicas == 1 {
+			for _, nad := range nads {
+				index, _ := replicas.GetIndex(nad.GetName(), name)
+				if mac := macalloc.GetReplica(itfce, index); mac != "" {
+					nadMacs[nad.GetName()] = mac
+				}
+			}
+		}
+	}
+	// release the allocations of interfaces and replicas that no longer exist
+	for _, key := range backend.Keys() {
+		if _, ok := keys[key]; !ok {
+			backend.Release(key)
+		}
+	}
+
+	for _, o := range rl.Items.Where(fn.IsGroupVersionKind(nadGVK)) {
+		if _, ok := nadMacs[o.GetName()]; !ok {
+			continue
+		}
+		nad, err := nadlibv1.NewFromKubeObject(o)
+		if err != nil {
+			rl.Results = append(rl.Results, fn.ErrorConfigObjectResult(err, o))
+			return false, nil
+		}
+		if err := nad.SetMacCapability(true); err != nil {
+			rl.Results = append(rl.Results, fn.ErrorConfigObjectResult(err, o))
+			return false, nil
+		}
+	}
+
+	// workloads that already select the networks get the mac address of
+	// the nad they select, the other workloads are handled by the multus fn
+	for _, workload := range rl.Items.Where(dependson.IsWorkload) {
+		if err := setMacRequest(workload, nadMacs); err != nil {
+			rl.Results = append(rl.Results, fn.ErrorConfigObjectResult(err, workload))
+			return false, nil
+		}
+	}
+
+	cm, err := backend.KubeObject(pkgCtx.Namespace)
+	if err != nil {
+		rl.Results.ErrorE(err)
+		return false, nil
+	}
+	return true, rl.UpsertObjectToItems(cm, nil, true)
+}
+
+// getPool returns the pool the mac addresses are allocated from, with the
+// prefix of the function config or else derived from the site code
+func getPool(rl *fn.ResourceList) (macalloc.Pool, error) {
+	if rl.FunctionConfig != nil {
+		if prefix, ok, err := rl.FunctionConfig.NestedString("data", prefixConfigKey); err == nil && ok && prefix != "" {
+			return macalloc.ParsePool(prefix)
+		}
+	}
+	site, err := getSiteCode(rl.Items)
+	if err != nil {
+		return macalloc.Pool{}, err
+	}
+	return macalloc.NewPool(site), nil
+}
+
+// getSiteCode returns the site code of the cluster context in the package,
+// the site code selects the pool the mac addresses are allocated from
+func getSiteCode(objs fn.KubeObjects) (string, error) {
+	site := ""
+	for _, o := range objs.Where(fn.IsGroupVersionKind(clusterContextGVK)) {
+		siteCode, _, err := o.NestedString("spec", "siteCode")
+		if err != nil {
+			return "", err
+		}
+		if site != "" && siteCode != site {
+			return "", fmt.Errorf("multiple ClusterContext objects with confliciting `siteCode` fields found in the package")
+		}
+		site = siteCode
+	}
+	if site == "" {
+		return "", fmt.Errorf("mandatory field `siteCode` is missing from the ClusterContext in the package")
+	}
+	return site, nil
+}
+
+// getMacReplicas returns the amount of replicas of the interface that need a
+// mac address, which is none if the interface is not attached through a nad.
+// Interfaces attached to the default pod network and loopback interfaces
+// dont have a nad.
+func getMacReplicas(itfce *fn.KubeObject) (int, error) {
+	ni, _, err := itfce.NestedString("spec", "networkInstance", "name")
+	if err != nil {
+		return 0, err
+	}
+	cniType, _, err := itfce.NestedString("spec", "cniType")
+	if err != nil {
+		return 0, err
+	}
+	if ni == defaultPODNetwork || cniType == "" {
+		return 0, nil
+	}
+	replicaStatus, _, err := itfce.NestedSlice(replicas.StatusField...)
+	if err != nil {
+		return 0, err
+	}
+	if len(replicaStatus) > 1 {
+		return len(replicaStatus), nil
+	}
+	return 1, nil
+}
+
+// setMacRequest sets the mac address in the network selection elements of
+// the workload selecting the nads pinning a mac address
+func setMacRequest(workload *fn.KubeObject, nadMacs map[string]string) error {
+	elems, err := multus.GetNetworkSelection(workload)
+	if err != nil {
+		return err
+	}
+	if len(elems) == 0 {
+		return nil
+	}
+	for i := range elems {
+		if mac, ok := nadMacs[elems[i].Name]; ok {
+			elems[i].MacRequest = mac
+		}
+	}
+	return multus.SetNetworkSelection(workload, elems)
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"os"
+
+	"github.com/GoogleContainerTools/kpt-functions-sdk/go/fn"
+	"github.com/henderiw-nephio/pkg-examples/multusfn/mutator"
+	"github.com/henderiw-nephio/pkg-examples/pkg/fnmeta"
+)
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == fnmeta.DescribeArg {
+		if err := fnmeta.Print(os.Stdout, mutator.Describe()); err != nil {
+			os.Exit(1)
+		}
+		return
+	}
+	if err := fn.AsMain(fn.ResourceListProcessorFunc(mutator.Run)); err != nil {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,203 @@
+package mutator
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/GoogleContainerTools/kpt-functions-sdk/go/fn"
+	"github.com/henderiw-nephio/pkg-examples/pkg/dependson"
+	"github.com/henderiw-nephio/pkg-examples/pkg/fnmeta"
+	"github.com/henderiw-nephio/pkg-examples/pkg/macalloc"
+	"github.com/henderiw-nephio/pkg-examples/pkg/multus"
+	nadlibv1 "github.com/henderiw-nephio/pkg-examples/pkg/nad/v1"
+	"github.com/henderiw-nephio/pkg-examples/pkg/pkgcontext"
+	"github.com/henderiw-nephio/pkg-examples/pkg/replicas"
+	nadv1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	fnName            = "multus-fn"
+	defaultPODNetwork = "defaultPODNetwork"
+)
+
+var (
+	interfaceGVK = schema.GroupVersionKind{Group: "req.nephio.org", Version: "v1alpha1", Kind: "Interface"}
+	nadGVK       = nadv1.SchemeGroupVersion.WithKind(reflect.TypeOf(nadv1.NetworkAttachmentDefinition{}).Name())
+	// upfDeploymentGVK describes the workloads the function injects the
+	// network selections in
+	upfDeploymentGVK = schema.GroupVersionKind{Group: "workload.nephio.org", Version: "v1alpha1", Kind: "UPFDeployment"}
+)
+
+// Describe returns the description of the function, the UPFDeployment stands
+// for the workloads of the package
+func Describe() fnmeta.Description {
+	d := fnmeta.Description{
+		Name:  fnName,
+		For:   fnmeta.NewResource(upfDeploymentGVK),
+		Watch: []fnmeta.Resource{fnmeta.NewResource(interfaceGVK), fnmeta.NewResource(nadGVK)},
+	}
+	d.Sort()
+	return d
+}
+
+// Run injects the multus network selection annotation in the workloads of
+// the package based on the interfaces and nads in the package. The network
+// selections of other nads in the annotation are kept.
+func Run(rl *fn.ResourceList) (bool, error) {
+	workloads := rl.Items.Where(dependson.IsWorkload)
+	if len(workloads) == 0 {
+		rl.Results.Infof("no workloads found in the package")
+		return true, nil
+	}
+
+	// the nads derived from the interfaces are selected by this function, the
+	// selections of other nads added by the user are kept
+	pkgCtx := pkgcontext.New(rl.Items)
+	nads := rl.Items.Where(fn.IsGroupVersionKind(nadGVK))
+	managedNads := map[multus.Key]bool{}
+	itfceNames := map[string]bool{}
+	for _, itfce := range rl.Items.Where(fn.IsGroupVersionKind(interfaceGVK)) {
+		itfceNames[itfce.GetName()] = true
+		for _, nad := range replicas.Filter(nads, itfce, pkgCtx.ChildName(itfce.GetName())) {
+			managedNads[multus.Key{Namespace: nad.GetNamespace(), Name: nad.GetName()}] = true
+		}
+	}
+
+	for _, workload := range workloads {
+		elems := []nadv1.NetworkSelectionElement{}
+		for _, itfce := range rl.Items.Where(fn.IsGroupVersionKind(interfaceGVK)) {
+			itfceElems, err := getNetworkSelectionElements(itfce, pkgCtx.ChildName(itfce.GetName()), rl.Items, multus.HasPodTemplate(workload))
+			if err != nil {
+				rl.Results = append(rl.Results, fn.ErrorConfigObjectResult(err, itfce))
+				return false, nil
+			}
+			elems = append(elems, itfceElems...)
+		}
+		// an element requesting an interface of the package is managed as
+		// well, such that the nads of replicas that are gone are unselected
+		isManaged := func(elem nadv1.NetworkSelectionElement) bool {
+			return managedNads[multus.GetKey(workload, elem)] || itfceNames[elem.InterfaceRequest]
+		}
+		if err := multus.MergeNetworkSelection(workload, elems, isManaged); err != nil {
+			rl.Results = append(rl.Results, fn.ErrorConfigObjectResult(err, workload))
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// getNetworkSelectionElements returns the network selection elements of the
+// interface whose children are named after name, none are returned if the
+// interface is not attached through a nad. An interface with a nad per
+// replica gets an element per replica pinning the address of the replica,
+// which only a workload that is not a pod template can select per replica.
+func getNetworkSelectionElements(itfce *fn.KubeObject, name string, objs fn.KubeObjects, podTemplate bool) ([]nadv1.NetworkSelectionElement, error) {
+	// interfaces attached to the default pod network and loopback interfaces
+	// dont have a nad
+	ni, _, err := itfce.NestedString("spec", "networkInstance", "name")
+	if err != nil {
+		return nil, err
+	}
+	cniType, _, err := itfce.NestedString("spec", "cniType")
+	if err != nil {
+		return nil, err
+	}
+	if ni == defaultPODNetwork || cniType == "" {
+		return nil, nil
+	}
+
+	nads := replicas.Filter(objs.Where(fn.IsGroupVersionKind(nadGVK)), itfce, name)
+	if len(nads) == 0 {
+		return nil, fmt.Errorf("nad for interface %q not found", itfce.GetName())
+	}
+	if len(nads) > 1 && podTemplate {
+		return nil, fmt.Errorf("interface %q has a nad per replica, which cannot be selected per pod in a pod template, set annotation %s to %s or %s to share a nad",
+			itfce.GetName(), nadlibv1.IpamTypeAnnotation, nadlibv1.IpamTypeWhereabouts, nadlibv1.IpamTypeHostLocal)
+	}
+	prefixes, err := getReplicaPrefixes(itfce)
+	if err != nil {
+		return nil, err
+	}
+
+	elems := []nadv1.NetworkSelectionElement{}
+	for _, o := range nads {
+		nad, err := nadlibv1.NewFromKubeObject(o)
+		if err != nil {
+			return nil, err
+		}
+		elem := nadv1.NetworkSelectionElement{
+			Name:             nad.GetName(),
+			Namespace:        nad.GetNamespace(),
+			InterfaceRequest: itfce.GetName(),
+			IPRequest:        []string{},
+		}
+		// the ips are only pinned through a nad with static ipam, the other
+		// ipam types hand out the addresses themselves. They are taken from
+		// the ip allocation status of the replica and fallback to the static
+		// addresses in the nad config.
+		index, _ := replicas.GetIndex(nad.GetName(), name)
+		ipam := getIpam(nad)
+		if ipam.Type == nadlibv1.IpamTypeStatic {
+			if index < len(prefixes) && prefixes[index] != "" {
+				elem.IPRequest = append(elem.IPRequest, prefixes[index])
+			} else {
+				for _, address := range ipam.Addresses {
+					elem.IPRequest = append(elem.IPRequest, address.Address)
+				}
+			}
+			sort.Strings(elem.IPRequest)
+		}
+		// a nad shared by several replicas cannot pin the mac address of a
+		// replica
+		if len(nads) > 1 || len(prefixes) <= 1 {
+			elem.MacRequest = macalloc.GetReplica(itfce, index)
+		}
+		elems = append(elems, elem)
+	}
+	return elems, nil
+}
+
+// getReplicaPrefixes returns the prefixes allocated to the replicas of the
+// interface by replica index
+func getReplicaPrefixes(itfce *fn.KubeObject) ([]string, error) {
+	prefixes := []string{}
+	replicaStatus, _, err := itfce.NestedSlice(replicas.StatusField...)
+	if err != nil {
+		return nil, err
+	}
+	if len(replicaStatus) > 1 {
+		for _, status := range replicaStatus {
+			prefix, _, err := status.NestedString("prefix")
+			if err != nil {
+				return nil, err
+			}
+			prefixes = append(prefixes, prefix)
+		}
+		return prefixes, nil
+	}
+	prefix, _, err := itfce.NestedString("status", "ipAllocationStatus", "prefix")
+	if err != nil {
+		return nil, err
+	}
+	if prefix != "" {
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes, nil
+}
+
+// getIpam returns the ipam of the nad config
+func getIpam(nad *nadlibv1.Nad) nadlibv1.Ipam {
+	nadConfigStruct := nadlibv1.NadConfig{}
+	if err := json.Unmarshal([]byte(nad.GetConfigSpec()), &nadConfigStruct); err != nil {
+		return nadlibv1.Ipam{}
+	}
+	for _, plugin := range nadConfigStruct.Plugins {
+		if plugin.Ipam.Type != "" {
+			return plugin.Ipam
+		}
+	}
+	return nadlibv1.Ipam{}
+}
@@ -1,65 +1,176 @@
 package mutator
 
 import (
+	"encoding/json"
 	"fmt"
 	"reflect"
+	"sort"
+	"strconv"
 
-	ko "github.com/henderiw-nephio/pkg-examples/pkg/kubeobject"
-	"github.com/nephio-project/nephio/krm-functions/lib/condkptsdk"
 	"github.com/GoogleContainerTools/kpt-functions-sdk/go/fn"
-	nephioreqv1alpha1 "github.com/nephio-project/api/nf_requirements/v1alpha1"
+	"github.com/henderiw-nephio/pkg-examples/pkg/dependson"
+	"github.com/henderiw-nephio/pkg-examples/pkg/fnmeta"
+	ko "github.com/henderiw-nephio/pkg-examples/pkg/kubeobject"
+	"github.com/henderiw-nephio/pkg-examples/pkg/localconfig"
+	"github.com/henderiw-nephio/pkg-examples/pkg/macalloc"
+	nadlibv1 "github.com/henderiw-nephio/pkg-examples/pkg/nad/v1"
+	"github.com/henderiw-nephio/pkg-examples/pkg/pkgcontext"
+	"github.com/henderiw-nephio/pkg-examples/pkg/replicas"
 	nadv1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	nfdeployv1alpha1 "github.com/nephio-project/api/nf_deployments/v1alpha1"
+	nephioreqv1alpha1 "github.com/nephio-project/api/nf_requirements/v1alpha1"
 	infrav1alpha1 "github.com/nephio-project/nephio-controller-poc/apis/infra/v1alpha1"
+	"github.com/nephio-project/nephio/krm-functions/lib/condkptsdk"
+	kptfilelibv1 "github.com/nephio-project/nephio/krm-functions/lib/kptfile/v1"
 	ipamv1alpha1 "github.com/nokia/k8s-ipam/apis/alloc/ipam/v1alpha1"
 	vlanv1alpha1 "github.com/nokia/k8s-ipam/apis/alloc/vlan/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const (
+	fnName = "nad-fn"
+	// ipamTypeConfigKey is the function config key to set the ipam type of all nads
+	ipamTypeConfigKey = "ipamType"
+	// bandwidthConfigKey is the function config key to chain the bandwidth
+	// plugin derived from the capacity to all nads
+	bandwidthConfigKey = "bandwidth"
+)
+
 type mutatorCtx struct {
 	sdk             condkptsdk.KptCondSDK
+	pkgCtx          *pkgcontext.PackageContext
 	masterInterface string
 	cniType         string
 	siteCode        string
+	// pools contains the allocated data network pool prefixes per network instance
+	pools map[string][]string
+	// ipamType is the default ipam type of the generated nads
+	ipamType string
+	// bandwidth enables the bandwidth plugin, the ingress and egress rates
+	// are derived from the max uplink and downlink throughput of the capacity
+	bandwidth   bool
+	ingressRate int64
+	egressRate  int64
 }
 
 func Run(rl *fn.ResourceList) (bool, error) {
-	m := mutatorCtx{}
+	m := mutatorCtx{
+		pkgCtx:   pkgcontext.New(rl.Items),
+		pools:    map[string][]string{},
+		ipamType: nadlibv1.IpamTypeStatic,
+	}
+	if rl.FunctionConfig != nil {
+		if ipamType, ok, err := rl.FunctionConfig.NestedString("data", ipamTypeConfigKey); err == nil && ok && ipamType != "" {
+			if !nadlibv1.IsIpamTypeSupported(ipamType) {
+				rl.Results = append(rl.Results, fn.ErrorConfigObjectResult(fmt.Errorf("unsupported ipam type: %s", ipamType), rl.FunctionConfig))
+				return false, nil
+			}
+			m.ipamType = ipamType
+		}
+		if bandwidth, ok, err := rl.FunctionConfig.NestedString("data", bandwidthConfigKey); err == nil && ok && bandwidth != "" {
+			m.bandwidth, err = strconv.ParseBool(bandwidth)
+			if err != nil {
+				rl.Results = append(rl.Results, fn.ErrorConfigObjectResult(fmt.Errorf("invalid %s value: %s", bandwidthConfigKey, bandwidth), rl.FunctionConfig))
+				return false, nil
+			}
+		}
+	}
+	// the capacity is optional and only provides the rates of the bandwidth
+	// plugin, hence it is not watched to avoid blocking the readiness
+	if m.bandwidth {
+		for _, o := range rl.Items.Where(fn.IsGroupVersionKind(nephioreqv1alpha1.CapacityGroupVersionKind)) {
+			var err error
+			if m.ingressRate, err = getCapacityRate(o, "maxUplinkThroughput"); err != nil {
+				rl.Results = append(rl.Results, fn.ErrorConfigObjectResult(err, o))
+				return false, nil
+			}
+			if m.egressRate, err = getCapacityRate(o, "maxDownlinkThroughput"); err != nil {
+				rl.Results = append(rl.Results, fn.ErrorConfigObjectResult(err, o))
+				return false, nil
+			}
+		}
+	}
 	var err error
-	m.sdk, err = condkptsdk.New(
-		rl,
-		&condkptsdk.Config{
-			For: corev1.ObjectReference{
-				APIVersion: nadv1.SchemeGroupVersion.Identifier(),
-				Kind:       reflect.TypeOf(nadv1.NetworkAttachmentDefinition{}).Name(),
-			},
-			Watch: map[corev1.ObjectReference]condkptsdk.WatchCallbackFn{
-				{
-					APIVersion: infrav1alpha1.GroupVersion.Identifier(),
-					Kind:       reflect.TypeOf(infrav1alpha1.ClusterContext{}).Name(),
-				}: m.ClusterContextCallbackFn,
-				{
-					APIVersion: ipamv1alpha1.GroupVersion.Identifier(),
-					Kind:       ipamv1alpha1.IPAllocationKind,
-				}: nil,
-				{
-					APIVersion: vlanv1alpha1.GroupVersion.Identifier(),
-					Kind:       vlanv1alpha1.VLANAllocationKind,
-				}: nil,
-				{
-					APIVersion: nephioreqv1alpha1.GroupVersion.Identifier(),
-					Kind:       nephioreqv1alpha1.InterfaceKind,
-				}: nil,
-			},
-			PopulateOwnResourcesFn: nil,
-			GenerateResourceFn:     m.updateNadResource,
-		},
-	)
+	m.sdk, err = condkptsdk.New(rl, m.config())
 	if err != nil {
 		rl.Results.ErrorE(err)
 		return false, nil
 	}
-	return m.sdk.Run()
+	ok, err := m.sdk.Run()
+	if err != nil || !ok {
+		return ok, err
+	}
+	if err := m.addWorkloadDependencies(rl); err != nil {
+		rl.Results.ErrorE(err)
+		return false, nil
+	}
+	return true, nil
+}
+
+// config returns the condkptsdk config of the function
+func (r *mutatorCtx) config() *condkptsdk.Config {
+	return &condkptsdk.Config{
+		For: corev1.ObjectReference{
+			APIVersion: nadv1.SchemeGroupVersion.Identifier(),
+			Kind:       reflect.TypeOf(nadv1.NetworkAttachmentDefinition{}).Name(),
+		},
+		Watch: map[corev1.ObjectReference]condkptsdk.WatchCallbackFn{
+			{
+				APIVersion: infrav1alpha1.GroupVersion.Identifier(),
+				Kind:       reflect.TypeOf(infrav1alpha1.ClusterContext{}).Name(),
+			}: r.ClusterContextCallbackFn,
+			{
+				APIVersion: ipamv1alpha1.GroupVersion.Identifier(),
+				Kind:       ipamv1alpha1.IPAllocationKind,
+			}: nil,
+			{
+				APIVersion: vlanv1alpha1.GroupVersion.Identifier(),
+				Kind:       vlanv1alpha1.VLANAllocationKind,
+			}: nil,
+			{
+				APIVersion: nephioreqv1alpha1.GroupVersion.Identifier(),
+				Kind:       nephioreqv1alpha1.InterfaceKind,
+			}: nil,
+			{
+				APIVersion: nephioreqv1alpha1.GroupVersion.Identifier(),
+				Kind:       nephioreqv1alpha1.DataNetworkKind,
+			}: r.DataNetworkCallbackFn,
+		},
+		PopulateOwnResourcesFn: nil,
+		GenerateResourceFn:     r.updateNadResource,
+	}
+}
+
+// Describe returns the description of the for, owned and watched resources of
+// the function
+func Describe() fnmeta.Description {
+	cfg := (&mutatorCtx{}).config()
+	return fnmeta.NewDescription(fnName, cfg.For, cfg.Owns, cfg.Watch)
+}
+
+// addWorkloadDependencies sets the depends-on annotation on the workloads in
+// the package so the nads derived from the interfaces are applied before the
+// workload pods referencing them. The UPFDeployments get the dependencies on
+// their nads from nfdeploy-fn.
+func (r *mutatorCtx) addWorkloadDependencies(rl *fn.ResourceList) error {
+	refs := []dependson.Ref{}
+	for _, nad := range rl.Items.Where(fn.IsGroupVersionKind(nadv1.SchemeGroupVersion.WithKind(reflect.TypeOf(nadv1.NetworkAttachmentDefinition{}).Name()))) {
+		ownerRef := kptfilelibv1.GetGVKNFromConditionType(nad.GetAnnotation(condkptsdk.SpecializerOwner))
+		if ownerRef.Kind != nephioreqv1alpha1.InterfaceKind {
+			continue
+		}
+		refs = append(refs, dependson.NewRef(nad))
+	}
+	if len(refs) == 0 {
+		return nil
+	}
+	for _, workload := range rl.Items.Where(dependson.IsWorkload).WhereNot(fn.IsGroupVersionKind(nfdeployv1alpha1.GroupVersion.WithKind(nfdeployv1alpha1.UPFDeploymentKind))) {
+		if err := dependson.Add(workload, dependson.Namespace(r.pkgCtx, workload), refs...); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (r *mutatorCtx) ClusterContextCallbackFn(o *fn.KubeObject) error {
@@ -96,22 +207,79 @@ func (r *mutatorCtx) updateNadResource(forObj *fn.KubeObject, objs fn.KubeObject
 	}
 	// generate an empty nad struct
 	meta := metav1.ObjectMeta{Name: objs[0].GetName()}
-		
+
+	var itfceGoStruct *nephioreqv1alpha1.Interface
+	var itfceObj *fn.KubeObject
+	ipamType := r.ipamType
 	itfces := objs.Where(fn.IsGroupVersionKind(nephioreqv1alpha1.InterfaceGroupVersionKind))
 	for _, itfce := range itfces {
+		// the nad inherits namespace and labels from the interface it is
+		// derived from, the name of a nad per replica is kept
+		name := r.pkgCtx.ChildName(itfce.GetName())
+		if forObj != nil {
+			name = forObj.GetName()
+		}
+		meta = r.pkgCtx.BuildObjectMeta(itfce, name, fnName)
+		itfceObj = itfce
+		// the ipam type can be overridden per interface
+		if t := itfce.GetAnnotation(nadlibv1.IpamTypeAnnotation); t != "" {
+			if !nadlibv1.IsIpamTypeSupported(t) {
+				return nil, fmt.Errorf("unsupported ipam type %s in annotation %s of interface %s", t, nadlibv1.IpamTypeAnnotation, itfce.GetName())
+			}
+			ipamType = t
+		}
 		ifce, err := ko.NewFromKubeObject[*nephioreqv1alpha1.Interface](itfce)
 		if err != nil {
 			return nil, err
 		}
-		itfceGoStruct, err := ifce.GetGoStruct()
+		itfceGoStruct, err = ifce.GetGoStruct()
 		if err != nil {
 			return nil, err
 		}
-		// set CNI Type
-		fn.Log(itfceGoStruct)
 	}
-	ipallocs := objs.Where(fn.IsGroupVersionKind(ipamv1alpha1.IPAllocationGroupVersionKind))
+	if itfceGoStruct == nil {
+		return nil, fmt.Errorf("expecting an interface to generate the nad %s", meta.Name)
+	}
+
+	// the mac address allocated to the replica is requested through the
+	// network selection, hence the nad pinning it needs the mac capability. A
+	// nad shared by several replicas cannot pin a mac address.
+	shared := nadlibv1.IsSharedIpamType(itfceObj.GetAnnotation(nadlibv1.IpamTypeAnnotation))
+	childName := r.pkgCtx.ChildName(itfceObj.GetName())
+	index, _ := replicas.GetIndex(meta.Name, childName)
+	macCapability := macalloc.GetReplica(itfceObj, index) != "" && (!shared || len(macalloc.Get(itfceObj)) == 1)
+
+	nadConfig := nadlibv1.NadConfig{
+		CniVersion: nadlibv1.CniVersion,
+		Plugins: []nadlibv1.PluginCniType{
+			{
+				Type: string(itfceGoStruct.Spec.CNIType),
+				// the multus fn pins the address of the replica through the
+				// network selection of a nad with static ipam
+				Capabilities: nadlibv1.Capabilities{
+					Ips: ipamType == nadlibv1.IpamTypeStatic,
+					Mac: macCapability,
+				},
+				Master: r.masterInterface,
+				Mode:   nadlibv1.NadMode,
+				Ipam: nadlibv1.Ipam{
+					Type: ipamType,
+				},
+			},
+		},
+	}
+
+	// every replica of the interface has its own ip allocation, sorted by
+	// replica index such that the generated ipam is stable across runs. A nad
+	// shared by the replicas hands out all their addresses, while the nad of
+	// a replica pins the address of that replica only.
+	prefixes := []string{}
+	gateway := ""
+	ipallocs := replicas.Filter(objs.Where(fn.IsGroupVersionKind(ipamv1alpha1.IPAllocationGroupVersionKind)), itfceObj, childName)
 	for _, ipalloc := range ipallocs {
+		if !shared && ipalloc.GetName() != meta.Name {
+			continue
+		}
 		alloc, err := ko.NewFromKubeObject[*ipamv1alpha1.IPAllocation](ipalloc)
 		if err != nil {
 			return nil, err
@@ -121,9 +289,24 @@ func (r *mutatorCtx) updateNadResource(forObj *fn.KubeObject, objs fn.KubeObject
 			return nil, err
 		}
 		// set IP
-		fn.Log(allocGoStruct)
+		if allocGoStruct.Status.Prefix == nil {
+			return nil, fmt.Errorf("ip allocation %s has no prefix allocated", alloc.GetName())
+		}
+		prefixes = append(prefixes, *allocGoStruct.Status.Prefix)
+		if allocGoStruct.Status.Gateway != nil && gateway == "" {
+			gateway = *allocGoStruct.Status.Gateway
+		}
+	}
+	if len(prefixes) > 0 {
+		ipam, err := nadlibv1.BuildReplicaIpam(ipamType, prefixes, gateway)
+		if err != nil {
+			return nil, err
+		}
+		// set routes to the data network pools reachable through the interface
+		ipam.Routes = r.getPoolRoutes(itfceGoStruct, gateway)
+		nadConfig.Plugins[0].Ipam = ipam
 	}
-	vlanallocs := objs.Where(fn.IsGroupVersionKind(ipamv1alpha1.IPAllocationGroupVersionKind))
+	vlanallocs := objs.Where(fn.IsGroupVersionKind(vlanv1alpha1.VLANAllocationGroupVersionKind))
 	for _, vlanalloc := range vlanallocs {
 		alloc, err := ko.NewFromKubeObject[*vlanv1alpha1.VLANAllocation](vlanalloc)
 		if err != nil {
@@ -134,10 +317,77 @@ func (r *mutatorCtx) updateNadResource(forObj *fn.KubeObject, objs fn.KubeObject
 			return nil, err
 		}
 		// set VLAN
-		fn.Log(allocGoStruct)
+		if allocGoStruct.Status.VLANID != nil {
+			nadConfig.Vlan = int(*allocGoStruct.Status.VLANID)
+		}
+	}
+
+	// chain the bandwidth plugin after the main plugin
+	if r.bandwidth && (r.ingressRate > 0 || r.egressRate > 0) {
+		nadConfig.Plugins = append(nadConfig.Plugins, nadlibv1.BuildBandwidthPlugin(r.ingressRate, r.egressRate))
 	}
 
-	return r.getNAD(meta, nadv1.NetworkAttachmentDefinitionSpec{})
+	b, err := json.Marshal(nadConfig)
+	if err != nil {
+		return nil, err
+	}
+	return r.getNAD(meta, nadv1.NetworkAttachmentDefinitionSpec{Config: string(b)})
+}
+
+// getCapacityRate returns the rate in bits per second of the throughput field
+// in the capacity spec, which is either a quantity such as 10G or a number
+func getCapacityRate(o *fn.KubeObject, field string) (int64, error) {
+	if s, ok, err := o.NestedString("spec", field); err == nil {
+		if !ok {
+			return 0, nil
+		}
+		return nadlibv1.ParseRate(s)
+	}
+	rate, _, err := o.NestedInt64("spec", field)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse %s of capacity %s, err: %v", field, o.GetName(), err)
+	}
+	return rate, nil
+}
+
+// DataNetworkCallbackFn provides a callback for the data network
+// resources in the resourceList
+func (r *mutatorCtx) DataNetworkCallbackFn(o *fn.KubeObject) error {
+	dnnKOE, err := ko.NewFromKubeObject[nephioreqv1alpha1.DataNetwork](o)
+	if err != nil {
+		return err
+	}
+	dnn, err := dnnKOE.GetGoStruct()
+	if err != nil {
+		return err
+	}
+	for _, pool := range dnn.Status.Pools {
+		if pool.IPAllocation.Prefix == nil {
+			continue
+		}
+		r.pools[dnn.Spec.NetworkInstance.Name] = append(r.pools[dnn.Spec.NetworkInstance.Name], *pool.IPAllocation.Prefix)
+	}
+	return nil
+}
+
+// getPoolRoutes returns a route for every data network pool allocated in the
+// network instance of the interface, via the gateway of the interface
+func (r *mutatorCtx) getPoolRoutes(itfce *nephioreqv1alpha1.Interface, gateway string) []nadlibv1.Route {
+	routes := []nadlibv1.Route{}
+	if itfce.Spec.NetworkInstance == nil || gateway == "" {
+		return routes
+	}
+	prefixes := map[string]struct{}{}
+	for _, prefix := range r.pools[itfce.Spec.NetworkInstance.Name] {
+		prefixes[prefix] = struct{}{}
+	}
+	for prefix := range prefixes {
+		routes = append(routes, nadlibv1.Route{Dst: prefix, Gw: gateway})
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		return routes[i].Dst < routes[j].Dst
+	})
+	return routes
 }
 
 func (r *mutatorCtx) getNAD(meta metav1.ObjectMeta, spec nadv1.NetworkAttachmentDefinitionSpec) (*fn.KubeObject, error) {
@@ -145,7 +395,15 @@ func (r *mutatorCtx) getNAD(meta metav1.ObjectMeta, spec nadv1.NetworkAttachment
 		meta,
 		spec,
 	)
-	return fn.NewFromTypedObject(nad)
+	o, err := fn.NewFromTypedObject(nad)
+	if err != nil {
+		return nil, err
+	}
+	// the nad is applied to the cluster
+	if err := localconfig.Set(o, false); err != nil {
+		return nil, err
+	}
+	return o, nil
 }
 
 func BuildNetworkAttachmentDefinition(meta metav1.ObjectMeta, spec nadv1.NetworkAttachmentDefinitionSpec) *nadv1.NetworkAttachmentDefinition {
@@ -157,4 +415,4 @@ func BuildNetworkAttachmentDefinition(meta metav1.ObjectMeta, spec nadv1.Network
 		ObjectMeta: meta,
 		Spec:       spec,
 	}
-}
\ No newline at end of file
+}
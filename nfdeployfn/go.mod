@@ -7,10 +7,12 @@ replace github.com/henderiw-nephio/pkg-examples => ../
 require (
 	github.com/GoogleContainerTools/kpt-functions-sdk/go/fn v0.0.0-20230302070146-e8e9cb3c3ae2
 	github.com/henderiw-nephio/pkg-examples v0.0.0-00010101000000-000000000000
+	github.com/k8snetworkplumbingwg/network-attachment-definition-client v1.4.0
 	github.com/nephio-project/api v0.0.0-20230427222620-ebcbeb2c21e3
 	github.com/nephio-project/nephio v0.0.0-20230430115622-89c76dea2d39
 	github.com/nephio-project/nephio-controller-poc v0.0.2
 	k8s.io/api v0.27.1
+	k8s.io/apimachinery v0.27.1
 )
 
 require (
@@ -56,7 +58,6 @@ require (
 	gopkg.in/inf.v0 v0.9.1 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
-	k8s.io/apimachinery v0.27.1 // indirect
 	k8s.io/client-go v0.27.1 // indirect
 	k8s.io/klog/v2 v2.90.1 // indirect
 	k8s.io/kube-openapi v0.0.0-20230327201221-f5883ff37f0c // indirect
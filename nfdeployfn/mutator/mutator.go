@@ -4,53 +4,85 @@ import (
 	"fmt"
 	"reflect"
 
-	ko "github.com/henderiw-nephio/pkg-examples/pkg/kubeobject"
 	"github.com/GoogleContainerTools/kpt-functions-sdk/go/fn"
+	"github.com/henderiw-nephio/pkg-examples/pkg/children"
+	"github.com/henderiw-nephio/pkg-examples/pkg/dependson"
+	"github.com/henderiw-nephio/pkg-examples/pkg/fnmeta"
+	ko "github.com/henderiw-nephio/pkg-examples/pkg/kubeobject"
+	"github.com/henderiw-nephio/pkg-examples/pkg/localconfig"
+	"github.com/henderiw-nephio/pkg-examples/pkg/pkgcontext"
+	nadv1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	nfdeployv1alpha1 "github.com/nephio-project/api/nf_deployments/v1alpha1"
 	nephioreqv1alpha1 "github.com/nephio-project/api/nf_requirements/v1alpha1"
 	infrav1alpha1 "github.com/nephio-project/nephio-controller-poc/apis/infra/v1alpha1"
 	"github.com/nephio-project/nephio/krm-functions/lib/condkptsdk"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
+const fnName = "nfdeploy-fn"
+
 type mutatorCtx struct {
 	sdk      condkptsdk.KptCondSDK
+	pkgCtx   *pkgcontext.PackageContext
+	capacity *fn.KubeObject
 	siteCode string
+	// nadRefs contains the nads derived from the interfaces in the package
+	nadRefs []dependson.Ref
 }
 
 func Run(rl *fn.ResourceList) (bool, error) {
-	m := mutatorCtx{}
+	m := mutatorCtx{
+		pkgCtx:  pkgcontext.New(rl.Items),
+		nadRefs: []dependson.Ref{},
+	}
+	// the capacity is optional and only provides the default replicas of the
+	// interfaces, hence it is not watched to avoid blocking the readiness
+	for _, o := range rl.Items.Where(fn.IsGroupVersionKind(nephioreqv1alpha1.CapacityGroupVersionKind)) {
+		m.capacity = o
+	}
 	var err error
-	m.sdk, err = condkptsdk.New(
-		rl,
-		&condkptsdk.Config{
-			For: corev1.ObjectReference{
-				APIVersion: "dummy",
-				Kind:       "NFDeployment",
-			},
-			Watch: map[corev1.ObjectReference]condkptsdk.WatchCallbackFn{
-				{
-					APIVersion: infrav1alpha1.GroupVersion.Identifier(),
-					Kind:       reflect.TypeOf(infrav1alpha1.ClusterContext{}).Name(),
-				}: m.ClusterContextCallbackFn,
-				{
-					APIVersion: nephioreqv1alpha1.GroupVersion.Identifier(),
-					Kind:       nephioreqv1alpha1.InterfaceKind,
-				}: nil,
-				{
-					APIVersion: nephioreqv1alpha1.GroupVersion.Identifier(),
-					Kind:       nephioreqv1alpha1.DataNetworkKind,
-				}: nil,
-			},
-			PopulateOwnResourcesFn: nil,
-			GenerateResourceFn:     m.updateNFDeployResource,
-		},
-	)
+	m.sdk, err = condkptsdk.New(rl, m.config())
 	if err != nil {
-		rl.Results = append(rl.Results, fn.ErrorConfigObjectResult(err, nil))
+		rl.Results.ErrorE(err)
+		return false, nil
 	}
 	return m.sdk.Run()
 }
 
+// config returns the condkptsdk config of the function
+func (r *mutatorCtx) config() *condkptsdk.Config {
+	return &condkptsdk.Config{
+		For: corev1.ObjectReference{
+			APIVersion: nfdeployv1alpha1.GroupVersion.Identifier(),
+			Kind:       nfdeployv1alpha1.UPFDeploymentKind,
+		},
+		Watch: map[corev1.ObjectReference]condkptsdk.WatchCallbackFn{
+			{
+				APIVersion: infrav1alpha1.GroupVersion.Identifier(),
+				Kind:       reflect.TypeOf(infrav1alpha1.ClusterContext{}).Name(),
+			}: r.ClusterContextCallbackFn,
+			{
+				APIVersion: nephioreqv1alpha1.GroupVersion.Identifier(),
+				Kind:       nephioreqv1alpha1.InterfaceKind,
+			}: r.InterfaceCallbackFn,
+			{
+				APIVersion: nephioreqv1alpha1.GroupVersion.Identifier(),
+				Kind:       nephioreqv1alpha1.DataNetworkKind,
+			}: nil,
+		},
+		PopulateOwnResourcesFn: nil,
+		GenerateResourceFn:     r.updateNFDeployResource,
+	}
+}
+
+// Describe returns the description of the for, owned and watched resources of
+// the function
+func Describe() fnmeta.Description {
+	cfg := (&mutatorCtx{}).config()
+	return fnmeta.NewDescription(fnName, cfg.For, cfg.Owns, cfg.Watch)
+}
+
 func (r *mutatorCtx) ClusterContextCallbackFn(o *fn.KubeObject) error {
 	clusterKOE, err := ko.NewFromKubeObject[*infrav1alpha1.ClusterContext](o)
 	if err != nil {
@@ -70,13 +102,68 @@ func (r *mutatorCtx) ClusterContextCallbackFn(o *fn.KubeObject) error {
 	return nil
 }
 
-func (r *mutatorCtx) updateNFDeployResource(forObj *fn.KubeObject, objs fn.KubeObjects) (*fn.KubeObject, error) {
-	// just testing for now this should be removed
-	if forObj != nil {
-		return nil, fmt.Errorf("expected a nil for object")
+// InterfaceCallbackFn provides a callback for the interface resources in
+// the resourceList. Every interface attached through a CNI results in a nad
+// per replica, or a shared nad, the nf deployment depends upon. nadfn leaves
+// the dependencies of the UPFDeployments to this function.
+func (r *mutatorCtx) InterfaceCallbackFn(o *fn.KubeObject) error {
+	if _, err := ko.NewFromKubeObject[*nephioreqv1alpha1.Interface](o); err != nil {
+		return err
 	}
+	// the nads are named by the same rules as in interfacefn, interfaces
+	// attached to the default pod network and loopback interfaces dont have
+	// a nad
+	names, err := children.Interface(r.pkgCtx, o, r.capacity)
+	if err != nil {
+		return err
+	}
+	// the nads get the namespace of the interface, a nad without namespace
+	// gets the namespace of the dependencies of the nf deployment
+	for _, name := range names[children.NADKind] {
+		r.nadRefs = append(r.nadRefs, dependson.Ref{
+			Group:     nadv1.SchemeGroupVersion.Group,
+			Kind:      reflect.TypeOf(nadv1.NetworkAttachmentDefinition{}).Name(),
+			Namespace: o.GetNamespace(),
+			Name:      name,
+		})
+	}
+	return nil
+}
 
-	// generate nfdeploy
+func (r *mutatorCtx) updateNFDeployResource(forObj *fn.KubeObject, objs fn.KubeObjects) (*fn.KubeObject, error) {
+	if forObj == nil {
+		return nil, fmt.Errorf("expected a for object but got nil")
+	}
+	nfDeployKOE, err := ko.NewFromKubeObject[*nfdeployv1alpha1.UPFDeployment](forObj)
+	if err != nil {
+		return nil, err
+	}
+	nfDeploy, err := nfDeployKOE.GetGoStruct()
+	if err != nil {
+		return nil, err
+	}
 
-	return nil, nil
+	// the nf deployment depends on the nads of its interfaces and the
+	// config it references
+	refs := append([]dependson.Ref{}, r.nadRefs...)
+	for _, configRef := range nfDeploy.Spec.ConfigRefs {
+		gv, err := schema.ParseGroupVersion(configRef.APIVersion)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, dependson.Ref{
+			Group:     gv.Group,
+			Kind:      configRef.Kind,
+			Namespace: configRef.Namespace,
+			Name:      configRef.Name,
+		})
+	}
+	if err := dependson.Add(&nfDeployKOE.KubeObject, dependson.Namespace(r.pkgCtx, forObj), refs...); err != nil {
+		return nil, err
+	}
+	// the nf deployment is applied to the cluster
+	if err := localconfig.Set(&nfDeployKOE.KubeObject, false); err != nil {
+		return nil, err
+	}
+	return &nfDeployKOE.KubeObject, nil
 }
@@ -0,0 +1,28 @@
+package mutator
+
+import (
+	"github.com/GoogleContainerTools/kpt-functions-sdk/go/fn"
+	"github.com/henderiw-nephio/pkg-examples/pkg/fnmeta"
+	nfdeployv1alpha1 "github.com/henderiw-nephio/pkg-examples/pkg/nfdeployment/v1alpha1"
+)
+
+const fnName = "nfdeploy-validator-fn"
+
+// Describe returns the description of the function, it validates the NF
+// deployments of every kind and has no for-object
+func Describe() fnmeta.Description {
+	return fnmeta.Description{Name: fnName, Validator: true}
+}
+
+// Run validates the NF deployments in the package against the typed API
+func Run(rl *fn.ResourceList) (bool, error) {
+	ok := true
+	for _, o := range rl.Items.Where(nfdeployv1alpha1.IsNFDeployment) {
+		results := nfdeployv1alpha1.Validate(o)
+		rl.Results = append(rl.Results, results...)
+		if len(results) > 0 {
+			ok = false
+		}
+	}
+	return ok, nil
+}
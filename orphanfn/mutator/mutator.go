@@ -0,0 +1,93 @@
+package mutator
+
+import (
+	"fmt"
+
+	"github.com/GoogleContainerTools/kpt-functions-sdk/go/fn"
+	"github.com/henderiw-nephio/pkg-examples/pkg/fnmeta"
+	"github.com/henderiw-nephio/pkg-examples/pkg/orphan"
+	"github.com/henderiw-nephio/pkg-examples/pkg/ownergraph"
+)
+
+const (
+	fnName = "orphan-fn"
+	// modeConfigKey is the function config key selecting validate, which
+	// reports the orphans, or gc, which removes them
+	modeConfigKey = "mode"
+
+	modeValidate = "validate"
+	modeGC       = "gc"
+)
+
+// Describe returns the description of the function, it acts on the whole
+// package and has no for-object
+func Describe() fnmeta.Description {
+	return fnmeta.Description{Name: fnName}
+}
+
+// Run reports the child resources and conditions whose owner no longer
+// exists in the package and removes them in gc mode
+func Run(rl *fn.ResourceList) (bool, error) {
+	mode := modeValidate
+	if rl.FunctionConfig != nil {
+		if m, ok, err := rl.FunctionConfig.NestedString("data", modeConfigKey); err == nil && ok && m != "" {
+			if m != modeValidate && m != modeGC {
+				rl.Results = append(rl.Results, fn.ErrorConfigObjectResult(fmt.Errorf("unsupported %s: %s", modeConfigKey, m), rl.FunctionConfig))
+				return false, nil
+			}
+			mode = m
+		}
+	}
+
+	pkg, err := ownergraph.NewPackage(rl.Items)
+	if err != nil {
+		rl.Results.ErrorE(err)
+		return false, nil
+	}
+	g, err := ownergraph.Build(pkg)
+	if err != nil {
+		rl.Results.ErrorE(err)
+		return false, nil
+	}
+	orphans := orphan.Find(g, fnmeta.Functions)
+
+	if mode == modeValidate {
+		for _, o := range orphans {
+			rl.Results = append(rl.Results, orphanResult(o, &pkg.Kptfile.KubeObject, fn.Error))
+		}
+		return len(orphans) == 0, nil
+	}
+
+	if err := orphan.Collect(pkg, orphans); err != nil {
+		rl.Results.ErrorE(err)
+		return false, nil
+	}
+	for _, o := range orphans {
+		rl.Results = append(rl.Results, orphanResult(o, &pkg.Kptfile.KubeObject, fn.Info))
+	}
+	// keep the order of the resource list, the Kptfile is replaced as the
+	// kptfile library works on a copy
+	kept := map[*fn.KubeObject]struct{}{}
+	for _, o := range pkg.Objects {
+		kept[o] = struct{}{}
+	}
+	items := fn.KubeObjects{}
+	for _, o := range rl.Items {
+		if o.GetKind() == pkg.Kptfile.GetKind() {
+			items = append(items, &pkg.Kptfile.KubeObject)
+			continue
+		}
+		if _, ok := kept[o]; ok {
+			items = append(items, o)
+		}
+	}
+	rl.Items = items
+	return true, nil
+}
+
+func orphanResult(o orphan.Orphan, kptfile *fn.KubeObject, severity fn.Severity) *fn.Result {
+	if o.Node.Object != nil {
+		return fn.ConfigObjectResult(o.String(), o.Node.Object, severity)
+	}
+	return fn.ConfigObjectResult(o.String(), kptfile, severity)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/henderiw-nephio/pkg-examples/pkg/ownergraph"
+)
+
+// ownergraph prints the owner graph of a package in the DOT or mermaid format.
+//
+//	ownergraph -package data/pkg-upf | dot -Tsvg > pkg-upf.svg
+//	ownergraph -package data/pkg-upf -format mermaid
+func main() {
+	pkg := flag.String("package", "", "directory of the package")
+	format := flag.String("format", "dot", "output format: dot or mermaid")
+	flag.Parse()
+
+	if *pkg == "" || (*format != "dot" && *format != "mermaid") {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	p, err := ownergraph.ReadPackage(*pkg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	g, err := ownergraph.Build(p)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *format == "mermaid" {
+		fmt.Print(g.Mermaid())
+		return
+	}
+	fmt.Print(g.DOT())
+}
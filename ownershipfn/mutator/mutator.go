@@ -0,0 +1,53 @@
+package mutator
+
+import (
+	"github.com/GoogleContainerTools/kpt-functions-sdk/go/fn"
+	"github.com/henderiw-nephio/pkg-examples/pkg/fnmeta"
+	"github.com/henderiw-nephio/pkg-examples/pkg/ownership"
+)
+
+const (
+	fnName = "ownership-fn"
+	// functionConfigKey is the function config key of the function that is
+	// about to modify the package, all functions are checked if not set
+	functionConfigKey = "function"
+)
+
+// Describe returns the description of the function, it validates the whole
+// package and has no for-object
+func Describe() fnmeta.Description {
+	return fnmeta.Description{Name: fnName, Validator: true}
+}
+
+// Run validates that the children in the package are owned by the for-object
+// of the function modifying them and that no two for-objects generate the
+// same child
+func Run(rl *fn.ResourceList) (bool, error) {
+	function := ""
+	if rl.FunctionConfig != nil {
+		if f, ok, err := rl.FunctionConfig.NestedString("data", functionConfigKey); err == nil && ok {
+			function = f
+		}
+	}
+
+	violations, err := ownership.CheckOwners(rl.Items, fnmeta.Functions, function)
+	if err != nil {
+		rl.Results = append(rl.Results, fn.ErrorConfigObjectResult(err, rl.FunctionConfig))
+		return false, nil
+	}
+	collisions, err := ownership.CheckCollisions(rl.Items, fnmeta.Functions)
+	if err != nil {
+		rl.Results.ErrorE(err)
+		return false, nil
+	}
+	violations = append(violations, collisions...)
+
+	valid := true
+	for _, v := range violations {
+		rl.Results = append(rl.Results, fn.ConfigObjectResult(v.Message, v.Object, v.Severity))
+		if v.Severity == fn.Error {
+			valid = false
+		}
+	}
+	return valid, nil
+}
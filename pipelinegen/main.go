@@ -0,0 +1,126 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/henderiw-nephio/pkg-examples/pkg/fnmeta"
+	kptfilev1 "github.com/henderiw-nephio/pkg-examples/pkg/kptfile/v1"
+)
+
+// pipelinegen orders functions from their descriptions, upstream functions
+// before the downstream functions depending on them, and writes the ordered
+// mutators and validators in the pipeline of the Kptfile. The descriptions are
+// the json printed by the functions in describe mode, the descriptions of the
+// functions in this repository are used if none are provided.
+//
+//	go run ./interfacefn describe > /tmp/interface-fn.json
+//	pipelinegen -package data/pkg-upf /tmp/*.json
+func main() {
+	pkg := flag.String("package", "", "directory of the package whose Kptfile pipeline is written")
+	registry := flag.String("registry", "europe-docker.pkg.dev/srlinux/eu.gcr.io", "registry of the function images without an image in their description")
+	tag := flag.String("tag", "latest", "tag of the function images without an image in their description")
+	flag.Parse()
+
+	fns := fnmeta.Functions
+	if flag.NArg() > 0 {
+		fns = []fnmeta.Description{}
+		for _, path := range flag.Args() {
+			b, err := os.ReadFile(path)
+			if err != nil {
+				log.Fatal(err)
+			}
+			descs, err := fnmeta.Parse(b)
+			if err != nil {
+				log.Fatalf("cannot parse %s: %v", path, err)
+			}
+			fns = append(fns, descs...)
+		}
+	}
+
+	ordered, err := fnmeta.Order(fns)
+	if err != nil {
+		log.Fatal(err)
+	}
+	mutators := []kptfilev1.Function{}
+	validators := []kptfilev1.Function{}
+	for _, f := range ordered {
+		image := f.Image
+		if image == "" {
+			image = fmt.Sprintf("%s/%s:%s", *registry, f.Name, *tag)
+		}
+		if f.Validator {
+			validators = append(validators, kptfilev1.Function{Image: image})
+			continue
+		}
+		fmt.Println(image)
+		mutators = append(mutators, kptfilev1.Function{Image: image})
+	}
+	for _, f := range validators {
+		fmt.Printf("%s (validator)\n", f.Image)
+	}
+	if *pkg == "" {
+		return
+	}
+
+	path := filepath.Join(*pkg, kptfilev1.KptfileName)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	kptfile, err := kptfilev1.NewFromYAML(b)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pipeline, err := kptfile.GetPipeline()
+	if err != nil {
+		log.Fatal(err)
+	}
+	pipeline.Mutators = merge(pipeline.Mutators, mutators)
+	pipeline.Validators = merge(pipeline.Validators, validators)
+	if err := kptfile.SetPipeline(*pipeline); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(kptfile.String()), 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// merge returns the ordered functions, keeping the config of the existing
+// functions with the same image, followed by the existing functions that are
+// not described
+func merge(existing, ordered []kptfilev1.Function) []kptfilev1.Function {
+	byImage := map[string]kptfilev1.Function{}
+	for _, f := range existing {
+		byImage[imageName(f.Image)] = f
+	}
+	merged := []kptfilev1.Function{}
+	described := map[string]struct{}{}
+	for _, f := range ordered {
+		name := imageName(f.Image)
+		described[name] = struct{}{}
+		if e, ok := byImage[name]; ok {
+			e.Image = f.Image
+			f = e
+		}
+		merged = append(merged, f)
+	}
+	for _, f := range existing {
+		if _, ok := described[imageName(f.Image)]; !ok {
+			merged = append(merged, f)
+		}
+	}
+	return merged
+}
+
+// imageName returns the image without its tag
+func imageName(image string) string {
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i]
+	}
+	return image
+}
@@ -0,0 +1,413 @@
+/*
+Copyright 2023 The Nephio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blueprint
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"text/template"
+
+	"github.com/GoogleContainerTools/kpt-functions-sdk/go/fn"
+	"github.com/henderiw-nephio/pkg-examples/pkg/localconfig"
+	"github.com/henderiw-nephio/pkg-examples/pkg/pkgcontext"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+const (
+	kptfileName     = "Kptfile"
+	reqAPIVersion   = "req.nephio.org/v1alpha1"
+	infraAPIVersion = "infra.nephio.org/v1alpha1"
+)
+
+// Profile is the compact description of an NF blueprint package
+type Profile struct {
+	// Name of the blueprint package
+	Name string `json:"name" yaml:"name"`
+	// Namespace of the package context, optional
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	// Description of the blueprint package
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	// NFType is the type of the NF, e.g. upf
+	NFType string `json:"nfType" yaml:"nfType"`
+	// SiteCode is the site code of the cluster context
+	SiteCode string `json:"siteCode" yaml:"siteCode"`
+	// CNIConfig is the cni config of the cluster context
+	CNIConfig CNIConfig `json:"cniConfig" yaml:"cniConfig"`
+	// Capacity of the NF, the throughput is expressed as a quantity such as 10G
+	Capacity *Capacity `json:"capacity,omitempty" yaml:"capacity,omitempty"`
+	// Interfaces of the NF
+	Interfaces []Interface `json:"interfaces,omitempty" yaml:"interfaces,omitempty"`
+	// DataNetworks reachable through the NF
+	DataNetworks []DataNetwork `json:"dataNetworks,omitempty" yaml:"dataNetworks,omitempty"`
+}
+
+type CNIConfig struct {
+	CNIType         string `json:"cniType" yaml:"cniType"`
+	MasterInterface string `json:"masterInterface" yaml:"masterInterface"`
+}
+
+type Capacity struct {
+	MaxUplinkThroughput   string `json:"maxUplinkThroughput,omitempty" yaml:"maxUplinkThroughput,omitempty"`
+	MaxDownlinkThroughput string `json:"maxDownlinkThroughput,omitempty" yaml:"maxDownlinkThroughput,omitempty"`
+	MaxSessions           int    `json:"maxSessions,omitempty" yaml:"maxSessions,omitempty"`
+	MaxSubscribers        int    `json:"maxSubscribers,omitempty" yaml:"maxSubscribers,omitempty"`
+	MaxNFConnections      uint16 `json:"maxNFConnections,omitempty" yaml:"maxNFConnections,omitempty"`
+}
+
+type Interface struct {
+	Name            string `json:"name" yaml:"name"`
+	NetworkInstance string `json:"networkInstance" yaml:"networkInstance"`
+	CNIType         string `json:"cniType,omitempty" yaml:"cniType,omitempty"`
+	AttachmentType  string `json:"attachmentType,omitempty" yaml:"attachmentType,omitempty"`
+}
+
+type DataNetwork struct {
+	Name            string `json:"name" yaml:"name"`
+	NetworkInstance string `json:"networkInstance" yaml:"networkInstance"`
+	Pools           []Pool `json:"pools,omitempty" yaml:"pools,omitempty"`
+}
+
+type Pool struct {
+	Name         string `json:"name" yaml:"name"`
+	PrefixLength uint8  `json:"prefixLength" yaml:"prefixLength"`
+}
+
+// ReadProfile reads the profile from the file
+func ReadProfile(path string) (*Profile, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p := &Profile{}
+	if err := yaml.Unmarshal(b, p); err != nil {
+		return nil, fmt.Errorf("cannot parse profile %s, err: %v", path, err)
+	}
+	return p, p.validate()
+}
+
+func (r *Profile) validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("profile name is required")
+	}
+	if r.SiteCode == "" {
+		return fmt.Errorf("profile siteCode is required")
+	}
+	names := map[string]struct{}{}
+	for _, itfce := range r.Interfaces {
+		if itfce.Name == "" || itfce.NetworkInstance == "" {
+			return fmt.Errorf("interface name and networkInstance are required")
+		}
+		if _, ok := names[itfce.Name]; ok {
+			return fmt.Errorf("duplicate interface %s", itfce.Name)
+		}
+		names[itfce.Name] = struct{}{}
+	}
+	for _, dnn := range r.DataNetworks {
+		if dnn.Name == "" || dnn.NetworkInstance == "" {
+			return fmt.Errorf("data network name and networkInstance are required")
+		}
+	}
+	return nil
+}
+
+// Generate returns the files of the blueprint package by file name, in the
+// same layout as the example packages in this repo
+func Generate(p *Profile) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	add := func(name string, x any) error {
+		b, err := yaml.Marshal(x)
+		if err != nil {
+			return err
+		}
+		files[name] = b
+		return nil
+	}
+
+	description := p.Description
+	if description == "" {
+		description = fmt.Sprintf("%s package example", p.NFType)
+	}
+	if err := add(kptfileName, kptfile{
+		APIVersion: "kpt.dev/v1",
+		Kind:       "Kptfile",
+		Metadata:   newMeta(p.Name),
+		Info:       info{Description: description},
+		Pipeline:   map[string]any{},
+	}); err != nil {
+		return nil, err
+	}
+
+	pkgCtxData := map[string]string{"name": p.Name}
+	if p.Namespace != "" {
+		pkgCtxData["namespace"] = p.Namespace
+	}
+	if err := add("package-context.yaml", object{
+		APIVersion: "v1",
+		Kind:       "ConfigMap",
+		Metadata:   newMeta(pkgcontext.ConfigMapName),
+		Data:       pkgCtxData,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := add("cluster_context.yaml", object{
+		APIVersion: infraAPIVersion,
+		Kind:       "ClusterContext",
+		Metadata:   newMeta("cluster-context"),
+		Spec: clusterContextSpec{
+			CNIConfig: p.CNIConfig,
+			SiteCode:  p.SiteCode,
+		},
+	}); err != nil {
+		return nil, err
+	}
+
+	if p.Capacity != nil {
+		if err := add("capacity.yaml", object{
+			APIVersion: reqAPIVersion,
+			Kind:       "Capacity",
+			Metadata:   newMeta("dataplane"),
+			Spec:       p.Capacity,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, dnn := range p.DataNetworks {
+		fileName := "dnn.yaml"
+		if len(p.DataNetworks) > 1 {
+			fileName = fmt.Sprintf("dnn-%s.yaml", dnn.Name)
+		}
+		if err := add(fileName, object{
+			APIVersion: reqAPIVersion,
+			Kind:       "DataNetwork",
+			Metadata:   newMeta(dnn.Name),
+			Spec: dataNetworkSpec{
+				NetworkInstance: reference{Name: dnn.NetworkInstance},
+				Pools:           dnn.Pools,
+			},
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, itfce := range p.Interfaces {
+		if err := add(fmt.Sprintf("interface-%s.yaml", itfce.Name), object{
+			APIVersion: reqAPIVersion,
+			Kind:       "Interface",
+			Metadata:   newMeta(itfce.Name),
+			Spec: interfaceSpec{
+				NetworkInstance: reference{Name: itfce.NetworkInstance},
+				CNIType:         itfce.CNIType,
+				AttachmentType:  itfce.AttachmentType,
+			},
+			Status: map[string]any{},
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := readmeTemplate.Execute(&buf, info{Name: p.Name, Description: description}); err != nil {
+		return nil, err
+	}
+	files["README.md"] = buf.Bytes()
+	return files, nil
+}
+
+// Write writes the files of the blueprint package to the directory
+func Write(dir string, files map[string][]byte) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for name, b := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), b, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Check compares the generated files with the package in the directory and
+// returns the differences. The objects are compared by kind and name on
+// their spec, such that the specialized resources the functions add to the
+// package and the status they set are ignored. The package context is not
+// compared since kpt provides it when the package is rendered.
+func Check(dir string, files map[string][]byte) ([]string, error) {
+	generated, err := parseFiles(files)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	paths = append(paths, filepath.Join(dir, kptfileName))
+	existingFiles := map[string][]byte{}
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		existingFiles[filepath.Base(path)] = b
+	}
+	existing, err := parseFiles(existingFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	diffs := []string{}
+	for key, o := range generated {
+		if o.GetKind() == "ConfigMap" && o.GetName() == pkgcontext.ConfigMapName {
+			continue
+		}
+		e, ok := existing[key]
+		if !ok {
+			diffs = append(diffs, fmt.Sprintf("%s: missing in %s", key, dir))
+			continue
+		}
+		fields := []string{"spec"}
+		if o.GetKind() == "Kptfile" {
+			fields = []string{"info"}
+		}
+		if !reflect.DeepEqual(getField(o, fields...), getField(e, fields...)) {
+			diffs = append(diffs, fmt.Sprintf("%s: %s differs", key, fields[0]))
+		}
+	}
+	for key, o := range existing {
+		if _, ok := generated[key]; ok || !isBlueprintKind(o) {
+			continue
+		}
+		diffs = append(diffs, fmt.Sprintf("%s: not generated from the profile", key))
+	}
+	sort.Strings(diffs)
+	return diffs, nil
+}
+
+// isBlueprintKind returns true for the kinds the generator emits, the other
+// kinds are added by the functions in the pipeline
+func isBlueprintKind(o *fn.KubeObject) bool {
+	switch o.GetKind() {
+	case "Kptfile", "ClusterContext", "Capacity", "DataNetwork", "Interface":
+		return true
+	}
+	return false
+}
+
+func parseFiles(files map[string][]byte) (map[string]*fn.KubeObject, error) {
+	objs := map[string]*fn.KubeObject{}
+	for name, b := range files {
+		if filepath.Ext(name) != ".yaml" && name != kptfileName {
+			continue
+		}
+		o, err := fn.ParseKubeObject(b)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse %s, err: %v", name, err)
+		}
+		objs[fmt.Sprintf("%s/%s", o.GetKind(), o.GetName())] = o
+	}
+	return objs, nil
+}
+
+func getField(o *fn.KubeObject, fields ...string) any {
+	x := map[string]any{}
+	if _, err := o.NestedResource(&x, fields...); err != nil {
+		return nil
+	}
+	return x
+}
+
+func newMeta(name string) meta {
+	return meta{
+		Name:        name,
+		Annotations: map[string]string{localconfig.Annotation: "true"},
+	}
+}
+
+type meta struct {
+	Name        string            `yaml:"name"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+type object struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   meta              `yaml:"metadata"`
+	Spec       any               `yaml:"spec,omitempty"`
+	Data       map[string]string `yaml:"data,omitempty"`
+	Status     any               `yaml:"status,omitempty"`
+}
+
+type kptfile struct {
+	APIVersion string         `yaml:"apiVersion"`
+	Kind       string         `yaml:"kind"`
+	Metadata   meta           `yaml:"metadata"`
+	Info       info           `yaml:"info"`
+	Pipeline   map[string]any `yaml:"pipeline"`
+}
+
+type info struct {
+	Name        string `yaml:"-"`
+	Description string `yaml:"description"`
+}
+
+type reference struct {
+	Name string `yaml:"name"`
+}
+
+type clusterContextSpec struct {
+	CNIConfig CNIConfig `yaml:"cniConfig"`
+	SiteCode  string    `yaml:"siteCode"`
+}
+
+type dataNetworkSpec struct {
+	NetworkInstance reference `yaml:"networkInstance"`
+	Pools           []Pool    `yaml:"pools,omitempty"`
+}
+
+type interfaceSpec struct {
+	NetworkInstance reference `yaml:"networkInstance"`
+	CNIType         string    `yaml:"cniType,omitempty"`
+	AttachmentType  string    `yaml:"attachmentType,omitempty"`
+}
+
+var readmeTemplate = template.Must(template.New("README.md").Parse(`# {{ .Name }}
+
+## Description
+{{ .Description }}
+
+## Usage
+
+### Fetch the package
+` + "`kpt pkg get REPO_URI[.git]/PKG_PATH[@VERSION] {{ .Name }}`" + `
+Details: https://kpt.dev/reference/cli/pkg/get/
+
+### View package content
+` + "`kpt pkg tree {{ .Name }}`" + `
+Details: https://kpt.dev/reference/cli/pkg/tree/
+
+### Apply the package
+` + "```" + `
+kpt live init {{ .Name }}
+kpt live apply {{ .Name }} --reconcile-timeout=2m --output=table
+` + "```" + `
+Details: https://kpt.dev/reference/cli/live/
+`))
@@ -0,0 +1,94 @@
+/*
+Copyright 2023 The Nephio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blueprint
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	testProfile = "../../data/upf.profile.yaml"
+	testPackage = "../../data/pkg-upf"
+)
+
+// TestGenerateMatchesPackage checks that the blueprint generated from the upf
+// profile round trips to the upf package in the repository
+func TestGenerateMatchesPackage(t *testing.T) {
+	p, err := ReadProfile(testProfile)
+	if err != nil {
+		t.Fatalf("cannot read profile: %v", err)
+	}
+	files, err := Generate(p)
+	if err != nil {
+		t.Fatalf("cannot generate blueprint: %v", err)
+	}
+	diffs, err := Check(testPackage, files)
+	if err != nil {
+		t.Fatalf("cannot check blueprint: %v", err)
+	}
+	if len(diffs) > 0 {
+		t.Errorf("generated blueprint differs from %s:\n%s", testPackage, strings.Join(diffs, "\n"))
+	}
+}
+
+// TestCheckReportsDifferences checks that a profile that no longer matches
+// the package is reported
+func TestCheckReportsDifferences(t *testing.T) {
+	cases := map[string]struct {
+		mutate func(p *Profile)
+		want   string
+	}{
+		"ChangedInterface": {
+			mutate: func(p *Profile) { p.Interfaces[0].NetworkInstance = "vpc-other" },
+			want:   "Interface/n3: spec differs",
+		},
+		"MissingInterface": {
+			mutate: func(p *Profile) { p.Interfaces = p.Interfaces[1:] },
+			want:   "Interface/n3: not generated from the profile",
+		},
+		"AddedDataNetwork": {
+			mutate: func(p *Profile) {
+				p.DataNetworks = append(p.DataNetworks, DataNetwork{Name: "ims", NetworkInstance: "vpc-internet"})
+			},
+			want: "DataNetwork/ims: missing in " + testPackage,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p, err := ReadProfile(testProfile)
+			if err != nil {
+				t.Fatalf("cannot read profile: %v", err)
+			}
+			tc.mutate(p)
+			files, err := Generate(p)
+			if err != nil {
+				t.Fatalf("cannot generate blueprint: %v", err)
+			}
+			diffs, err := Check(testPackage, files)
+			if err != nil {
+				t.Fatalf("cannot check blueprint: %v", err)
+			}
+			for _, diff := range diffs {
+				if diff == tc.want {
+					return
+				}
+			}
+			t.Errorf("expected %q in the differences, got %v", tc.want, diffs)
+		})
+	}
+}
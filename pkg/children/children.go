@@ -0,0 +1,109 @@
+/*
+Copyright 2023 The Nephio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package children
+
+import (
+	"fmt"
+
+	"github.com/GoogleContainerTools/kpt-functions-sdk/go/fn"
+	"github.com/henderiw-nephio/pkg-examples/pkg/pkgcontext"
+	"github.com/henderiw-nephio/pkg-examples/pkg/replicas"
+)
+
+const (
+	// the kinds of the children, as in the descriptions of the functions
+	IPAllocationKind   = "IPAllocation"
+	VLANAllocationKind = "VLANAllocation"
+	NADKind            = "NetworkAttachmentDefinition"
+
+	// defaultPODNetwork is handled by the cluster CNI, an interface attached
+	// to it has no children
+	defaultPODNetwork = "defaultPODNetwork"
+	// vlanAttachmentType is the attachment type of an interface that needs
+	// a vlan allocation
+	vlanAttachmentType = "vlan"
+)
+
+// Names are the names of the children of a for-object by kind
+type Names map[string][]string
+
+// Interface returns the names of the children of an interface: an ip
+// allocation per replica, a vlan allocation for a vlan attachment and the
+// nads of the replicas, or the loopback ip allocation of an interface without
+// cniType. The names are prefixed with the package name, see ChildName of the
+// package context. The replicas are taken from the interface or else the
+// capacity, which may be nil.
+func Interface(pkgCtx *pkgcontext.PackageContext, itfce, capacity *fn.KubeObject) (Names, error) {
+	names := Names{}
+	name := pkgCtx.ChildName(itfce.GetName())
+	ni, _, err := itfce.NestedString("spec", "networkInstance", "name")
+	if err != nil {
+		return nil, err
+	}
+	if ni == defaultPODNetwork {
+		return names, nil
+	}
+	cniType, _, err := itfce.NestedString("spec", "cniType")
+	if err != nil {
+		return nil, err
+	}
+	if cniType == "" {
+		names[IPAllocationKind] = []string{name}
+		return names, nil
+	}
+
+	nrReplicas, err := replicas.Get(itfce, capacity)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < nrReplicas; i++ {
+		names[IPAllocationKind] = append(names[IPAllocationKind], replicas.AllocationName(name, i))
+	}
+	attachmentType, _, err := itfce.NestedString("spec", "attachmentType")
+	if err != nil {
+		return nil, err
+	}
+	if attachmentType == vlanAttachmentType {
+		names[VLANAllocationKind] = []string{name}
+	}
+	names[NADKind] = replicas.NadNames(itfce, name, nrReplicas)
+	return names, nil
+}
+
+// DataNetwork returns the names of the children of a data network: an ip
+// allocation per pool, prefixed with the package name
+func DataNetwork(pkgCtx *pkgcontext.PackageContext, dnn *fn.KubeObject) (Names, error) {
+	pools, _, err := dnn.NestedSlice("spec", "pools")
+	if err != nil {
+		return nil, err
+	}
+	names := Names{}
+	for _, pool := range pools {
+		name, _, err := pool.NestedString("name")
+		if err != nil {
+			return nil, err
+		}
+		names[IPAllocationKind] = append(names[IPAllocationKind], PoolAllocationName(pkgCtx.ChildName(dnn.GetName()), name))
+	}
+	return names, nil
+}
+
+// PoolAllocationName returns the name of the ip allocation of a pool of the
+// data network, dnnName is the child name of the data network
+func PoolAllocationName(dnnName, poolName string) string {
+	return fmt.Sprintf("%s-%s", dnnName, poolName)
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2023 The Nephio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dependson
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt-functions-sdk/go/fn"
+	"github.com/henderiw-nephio/pkg-examples/pkg/pkgcontext"
+)
+
+const (
+	// Annotation is used by kpt live apply to order the apply of the
+	// resources in a package
+	Annotation = "config.kubernetes.io/depends-on"
+	// DefaultNamespace is the namespace kpt applies the namespaced resources
+	// without a namespace to
+	DefaultNamespace = "default"
+
+	namespacesSegment = "namespaces"
+)
+
+// Ref identifies a resource in the depends-on annotation.
+// The format is <group>/namespaces/<namespace>/<kind>/<name> for namespaced
+// resources and <group>/<kind>/<name> for cluster scoped resources
+type Ref struct {
+	Group     string
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// NewRef returns the Ref of the object
+func NewRef(o *fn.KubeObject) Ref {
+	return Ref{
+		Group:     o.GroupKind().Group,
+		Kind:      o.GetKind(),
+		Namespace: o.GetNamespace(),
+		Name:      o.GetName(),
+	}
+}
+
+// String returns the depends-on representation of the Ref
+func (r Ref) String() string {
+	if r.Namespace != "" {
+		return strings.Join([]string{r.Group, namespacesSegment, r.Namespace, r.Kind, r.Name}, "/")
+	}
+	return strings.Join([]string{r.Group, r.Kind, r.Name}, "/")
+}
+
+// ParseRef returns a Ref from its depends-on representation
+func ParseRef(s string) (Ref, error) {
+	split := strings.Split(strings.TrimSpace(s), "/")
+	switch {
+	case len(split) == 3:
+		return Ref{Group: split[0], Kind: split[1], Name: split[2]}, nil
+	case len(split) == 5 && split[1] == namespacesSegment:
+		return Ref{Group: split[0], Namespace: split[2], Kind: split[3], Name: split[4]}, nil
+	}
+	return Ref{}, fmt.Errorf("invalid depends-on reference: %q", s)
+}
+
+// Get returns the Refs in the depends-on annotation of the object
+func Get(o *fn.KubeObject) ([]Ref, error) {
+	refs := []Ref{}
+	a := o.GetAnnotation(Annotation)
+	if a == "" {
+		return refs, nil
+	}
+	for _, s := range strings.Split(a, ",") {
+		ref, err := ParseRef(s)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
+
+// Namespace returns the namespace of the dependencies of the object that have
+// no namespace of their own: the namespace of the package, else the namespace
+// of the object, else the default namespace. The package context may be nil.
+func Namespace(pkgCtx *pkgcontext.PackageContext, o *fn.KubeObject) string {
+	if pkgCtx != nil && pkgCtx.Namespace != "" {
+		return pkgCtx.Namespace
+	}
+	if o.GetNamespace() != "" {
+		return o.GetNamespace()
+	}
+	return DefaultNamespace
+}
+
+// Add adds the Refs to the depends-on annotation of the object, keeping the
+// existing Refs. Refs without a namespace get the namespace, as returned by
+// Namespace, since the dependencies are namespaced resources of the same
+// package. The resulting Refs are sorted to keep the annotation stable across
+// runs.
+func Add(o *fn.KubeObject, namespace string, refs ...Ref) error {
+	existingRefs, err := Get(o)
+	if err != nil {
+		return err
+	}
+	m := map[string]struct{}{}
+	for _, ref := range existingRefs {
+		m[ref.String()] = struct{}{}
+	}
+	for _, ref := range refs {
+		if ref.Namespace == "" {
+			ref.Namespace = namespace
+		}
+		m[ref.String()] = struct{}{}
+	}
+	if len(m) == 0 {
+		return nil
+	}
+	deps := make([]string, 0, len(m))
+	for s := range m {
+		deps = append(deps, s)
+	}
+	sort.Strings(deps)
+	return o.SetAnnotation(Annotation, strings.Join(deps, ","))
+}
+
+// IsWorkload returns true if the object is a workload that consumes the
+// generated prerequisites such as network attachment definitions
+func IsWorkload(o *fn.KubeObject) bool {
+	gk := o.GroupKind()
+	switch {
+	case gk.Group == "workload.nephio.org":
+		return true
+	case gk.Group == "apps" && (gk.Kind == "Deployment" || gk.Kind == "StatefulSet" || gk.Kind == "DaemonSet"):
+		return true
+	}
+	return false
+}
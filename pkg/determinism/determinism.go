@@ -0,0 +1,324 @@
+/*
+Copyright 2023 The Nephio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package determinism
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/henderiw-nephio/pkg-examples/pkg/dryrun"
+	kptfilev1 "github.com/henderiw-nephio/pkg-examples/pkg/kptfile/v1"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+const defaultRuns = 3
+
+// Config of a determinism check of a function on a package
+type Config struct {
+	// Package is the directory of the package the input ResourceList is read
+	// from, it is not modified
+	Package string
+	// Function is the executable with its arguments that reads and writes a
+	// ResourceList
+	Function []string
+	// FunctionConfig is passed to the function as the data of a ConfigMap,
+	// like the key=value arguments of kpt fn eval
+	FunctionConfig map[string]string
+	// Runs is the number of times the function is run on the package
+	Runs int
+}
+
+// Divergence is the first difference between the items of two outputs
+type Divergence struct {
+	// Path of the field that differs, e.g.
+	// items[0]{Kptfile/pkg-upf}.status.conditions[3]{type=...}.status
+	Path    string
+	Message string
+}
+
+func (r *Divergence) String() string {
+	return fmt.Sprintf("%s: %s", r.Path, r.Message)
+}
+
+// Result of a run compared to the output of the first run
+type Result struct {
+	// Name of the run, e.g. "run 2" or "rerun on output"
+	Name       string
+	Divergence *Divergence
+}
+
+// Report of a determinism check
+type Report struct {
+	Results []Result
+}
+
+// Deterministic returns true if no run diverged from the first one
+func (r *Report) Deterministic() bool {
+	for _, res := range r.Results {
+		if res.Divergence != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Report) String() string {
+	var sb strings.Builder
+	for _, res := range r.Results {
+		if res.Divergence == nil {
+			fmt.Fprintf(&sb, "%s: identical to run 1\n", res.Name)
+			continue
+		}
+		fmt.Fprintf(&sb, "%s: differs from run 1 at %s\n", res.Name, res.Divergence.String())
+	}
+	if r.Deterministic() {
+		sb.WriteString("deterministic\n")
+	} else {
+		sb.WriteString("not deterministic\n")
+	}
+	return sb.String()
+}
+
+// Check runs the function on the package the configured number of times and
+// once more on its own output, and compares the items of every output with
+// the output of the first run, including the order of the fields
+func Check(cfg *Config) (*Report, error) {
+	if len(cfg.Function) == 0 {
+		return nil, fmt.Errorf("no function to check")
+	}
+	runs := cfg.Runs
+	if runs <= 0 {
+		runs = defaultRuns
+	}
+	input, err := readPackage(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	first, err := run(cfg.Function, input)
+	if err != nil {
+		return nil, fmt.Errorf("run 1: %s", err.Error())
+	}
+	report := &Report{}
+	for i := 2; i <= runs; i++ {
+		name := fmt.Sprintf("run %d", i)
+		out, err := run(cfg.Function, input)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", name, err.Error())
+		}
+		report.Results = append(report.Results, Result{Name: name, Divergence: Compare(first, out)})
+	}
+
+	// a function that is run again on its own output must not change it, the
+	// results of the first run are not part of the input of the rerun
+	rerunInput := first.Copy()
+	if err := rerunInput.PipeE(yaml.Clear("results")); err != nil {
+		return nil, err
+	}
+	b, err := rerunInput.String()
+	if err != nil {
+		return nil, err
+	}
+	out, err := run(cfg.Function, []byte(b))
+	if err != nil {
+		return nil, fmt.Errorf("rerun on output: %s", err.Error())
+	}
+	report.Results = append(report.Results, Result{Name: "rerun on output", Divergence: Compare(first, out)})
+	return report, nil
+}
+
+// readPackage returns the ResourceList with the objects of the package, the
+// same way kpt passes them to a function
+func readPackage(cfg *Config) ([]byte, error) {
+	var fc *yaml.RNode
+	if cfg.FunctionConfig != nil {
+		var err error
+		fc, err = dryrun.NewFunctionConfig(cfg.FunctionConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var b bytes.Buffer
+	if err := (kio.Pipeline{
+		Inputs: []kio.Reader{&kio.LocalPackageReader{
+			PackagePath:    cfg.Package,
+			MatchFilesGlob: append(kio.MatchAll, kptfilev1.KptfileName),
+		}},
+		Outputs: []kio.Writer{kio.ByteWriter{
+			Writer:                &b,
+			KeepReaderAnnotations: true,
+			FunctionConfig:        fc,
+			WrappingKind:          kio.ResourceListKind,
+			WrappingAPIVersion:    kio.ResourceListAPIVersion,
+		}},
+	}).Execute(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// run runs the function on the input ResourceList and returns the output
+// ResourceList
+func run(f []string, input []byte) (*yaml.RNode, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(f[0], f[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s: %s", err.Error(), strings.TrimSpace(stderr.String()))
+	}
+	return yaml.Parse(stdout.String())
+}
+
+// Compare returns the first divergence between the items of two
+// ResourceLists, or nil if they are identical including the order of the
+// items and their fields
+func Compare(a, b *yaml.RNode) *Divergence {
+	return compare("items", items(a), items(b))
+}
+
+func items(rl *yaml.RNode) *yaml.Node {
+	if f := rl.Field("items"); f != nil {
+		return f.Value.YNode()
+	}
+	return &yaml.Node{Kind: yaml.SequenceNode}
+}
+
+func compare(path string, a, b *yaml.Node) *Divergence {
+	if a.Kind == yaml.DocumentNode && len(a.Content) > 0 {
+		a = a.Content[0]
+	}
+	if b.Kind == yaml.DocumentNode && len(b.Content) > 0 {
+		b = b.Content[0]
+	}
+	if a.Kind != b.Kind {
+		return &Divergence{Path: path, Message: fmt.Sprintf("%s in run 1, %s in the other run", kind(a), kind(b))}
+	}
+	switch a.Kind {
+	case yaml.ScalarNode:
+		if a.Value != b.Value || a.ShortTag() != b.ShortTag() {
+			return &Divergence{Path: path, Message: fmt.Sprintf("%q in run 1, %q in the other run", a.Value, b.Value)}
+		}
+	case yaml.SequenceNode:
+		for i := 0; i < len(a.Content) && i < len(b.Content); i++ {
+			if d := compare(fmt.Sprintf("%s[%d]%s", path, i, label(a.Content[i])), a.Content[i], b.Content[i]); d != nil {
+				return d
+			}
+		}
+		if n := len(b.Content); len(a.Content) > n {
+			return &Divergence{Path: fmt.Sprintf("%s[%d]%s", path, n, label(a.Content[n])), Message: "only in run 1"}
+		}
+		if n := len(a.Content); len(b.Content) > n {
+			return &Divergence{Path: fmt.Sprintf("%s[%d]%s", path, n, label(b.Content[n])), Message: "only in the other run"}
+		}
+	case yaml.MappingNode:
+		return compareMapping(path, a, b)
+	}
+	return nil
+}
+
+// compareMapping compares the fields of two mappings in order, such that a
+// different field order is reported as a divergence
+func compareMapping(path string, a, b *yaml.Node) *Divergence {
+	aKeys := keys(a)
+	bKeys := keys(b)
+	for i := 0; i < len(aKeys) && i < len(bKeys); i++ {
+		if aKeys[i] != bKeys[i] {
+			_, aInB := index(bKeys, aKeys[i])
+			_, bInA := index(aKeys, bKeys[i])
+			switch {
+			case aInB && bInA:
+				return &Divergence{Path: path, Message: fmt.Sprintf("field order %v in run 1, %v in the other run", aKeys, bKeys)}
+			case !aInB:
+				return &Divergence{Path: path + "." + aKeys[i], Message: "only in run 1"}
+			default:
+				return &Divergence{Path: path + "." + bKeys[i], Message: "only in the other run"}
+			}
+		}
+		if d := compare(path+"."+aKeys[i], a.Content[2*i+1], b.Content[2*i+1]); d != nil {
+			return d
+		}
+	}
+	if len(aKeys) > len(bKeys) {
+		return &Divergence{Path: path + "." + aKeys[len(bKeys)], Message: "only in run 1"}
+	}
+	if len(bKeys) > len(aKeys) {
+		return &Divergence{Path: path + "." + bKeys[len(aKeys)], Message: "only in the other run"}
+	}
+	return nil
+}
+
+func keys(n *yaml.Node) []string {
+	keys := make([]string, 0, len(n.Content)/2)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		keys = append(keys, n.Content[i].Value)
+	}
+	return keys
+}
+
+func index(keys []string, key string) (int, bool) {
+	for i, k := range keys {
+		if k == key {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+func field(n *yaml.Node, key string) *yaml.Node {
+	if n.Kind != yaml.MappingNode {
+		return nil
+	}
+	if i, ok := index(keys(n), key); ok {
+		return n.Content[2*i+1]
+	}
+	return nil
+}
+
+// label identifies an entry of a sequence in the path, objects by their kind
+// and name and conditions by their type
+func label(n *yaml.Node) string {
+	kind := field(n, "kind")
+	if md := field(n, "metadata"); kind != nil && md != nil {
+		if name := field(md, "name"); name != nil {
+			return fmt.Sprintf("{%s/%s}", kind.Value, name.Value)
+		}
+	}
+	for _, key := range []string{"type", "name"} {
+		if v := field(n, key); v != nil && v.Kind == yaml.ScalarNode {
+			return fmt.Sprintf("{%s=%s}", key, v.Value)
+		}
+	}
+	return ""
+}
+
+func kind(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		return "a scalar"
+	case yaml.SequenceNode:
+		return "a list"
+	case yaml.MappingNode:
+		return "a map"
+	default:
+		return "an alias"
+	}
+}
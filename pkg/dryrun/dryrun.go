@@ -0,0 +1,493 @@
+/*
+Copyright 2023 The Nephio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dryrun
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt-functions-sdk/go/fn"
+	kptfilev1 "github.com/henderiw-nephio/pkg-examples/pkg/kptfile/v1"
+	"github.com/henderiw-nephio/pkg-examples/pkg/readiness"
+	"github.com/pmezard/go-difflib/difflib"
+	"sigs.k8s.io/kustomize/kyaml/fn/runtime/exec"
+	"sigs.k8s.io/kustomize/kyaml/fn/runtime/runtimeutil"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/kio/kioutil"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// ChangeType is the type of change of an object or a condition
+type ChangeType string
+
+const (
+	ChangeTypeCreated ChangeType = "created"
+	ChangeTypeUpdated ChangeType = "updated"
+	ChangeTypeDeleted ChangeType = "deleted"
+)
+
+// Config of a dry-run of a function chain on a package
+type Config struct {
+	// Package is the directory of the package, it is not modified
+	Package string
+	// Functions is the function chain run on the package, every function is
+	// an executable with its arguments that reads and writes a ResourceList
+	Functions [][]string
+	// FunctionConfig is passed to every function as the data of a ConfigMap,
+	// like the key=value arguments of kpt fn eval
+	FunctionConfig map[string]string
+}
+
+// FileDiff is the unified diff of a file in the package
+type FileDiff struct {
+	Path string
+	Diff string
+}
+
+// ObjectChange is an object that is created, updated or deleted
+type ObjectChange struct {
+	Type ChangeType
+	Ref  readiness.Ref
+	Path string
+}
+
+// ConditionChange is a condition in the Kptfile that is created, updated or
+// deleted, Before is nil for a created condition and After for a deleted one
+type ConditionChange struct {
+	Type   ChangeType
+	Before *kptfilev1.Condition
+	After  *kptfilev1.Condition
+}
+
+// FunctionResults are the results reported by a function of the chain
+type FunctionResults struct {
+	Function string
+	Results  fn.Results
+}
+
+// Report of the changes a function makes to a package
+type Report struct {
+	Files      []FileDiff
+	Objects    []ObjectChange
+	Conditions []ConditionChange
+	Results    []FunctionResults
+}
+
+// Run runs the function chain on the package and reports the changes without
+// writing them. The chain stops at the first function that fails, the report
+// is returned together with the error such that the results of the failed
+// function and the changes up to its output are shown.
+func Run(cfg *Config) (*Report, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	var fc *yaml.RNode
+	if cfg.FunctionConfig != nil {
+		fc, err = NewFunctionConfig(cfg.FunctionConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+	c := &chain{}
+	for _, f := range cfg.Functions {
+		if len(f) == 0 {
+			continue
+		}
+		c.names = append(c.names, strings.Join(f, " "))
+		c.filters = append(c.filters, &exec.Filter{
+			Path:       f[0],
+			Args:       f[1:],
+			WorkingDir: wd,
+			FunctionFilter: runtimeutil.FunctionFilter{
+				GlobalScope:    true,
+				FunctionConfig: fc,
+				// the output and results of a failed function are kept
+				DeferFailure: true,
+			},
+		})
+	}
+
+	reader := &kio.LocalPackageReader{
+		PackagePath:    cfg.Package,
+		MatchFilesGlob: append(kio.MatchAll, kptfilev1.KptfileName),
+	}
+	var before, after kio.PackageBuffer
+	if err := (kio.Pipeline{Inputs: []kio.Reader{reader}, Outputs: []kio.Writer{&before}}).Execute(); err != nil {
+		return nil, err
+	}
+	if err := (kio.Pipeline{Inputs: []kio.Reader{reader}, Filters: []kio.Filter{c}, Outputs: []kio.Writer{&after}}).Execute(); err != nil {
+		return nil, err
+	}
+	// new objects are written to a file named after their kind and name, the
+	// same way kpt writes them to the package
+	if err := kioutil.DefaultPathAndIndexAnnotation("", after.Nodes); err != nil {
+		return nil, err
+	}
+	beforeObjs, err := toKubeObjects(before.Nodes)
+	if err != nil {
+		return nil, err
+	}
+	afterObjs, err := toKubeObjects(after.Nodes)
+	if err != nil {
+		return nil, err
+	}
+	report, err := Compare(beforeObjs, afterObjs)
+	if err != nil {
+		return nil, err
+	}
+	report.Results = c.results
+	return report, c.err
+}
+
+// chain runs the functions one after the other and stops at the first
+// function that fails, keeping its output and the results of every function
+type chain struct {
+	names   []string
+	filters []*exec.Filter
+	results []FunctionResults
+	// err is the error of the failed function
+	err error
+}
+
+func (r *chain) Filter(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
+	for i, f := range r.filters {
+		out, err := f.Filter(nodes)
+		if err != nil {
+			return nil, err
+		}
+		results, err := parseResults(f.Results)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse the results of function %s: %w", r.names[i], err)
+		}
+		if len(results) > 0 {
+			r.results = append(r.results, FunctionResults{Function: r.names[i], Results: results})
+		}
+		if exit := f.GetExit(); exit != nil {
+			r.err = fmt.Errorf("function %s failed: %w", r.names[i], exit)
+			// a function that fails without writing a ResourceList did not
+			// change the package
+			if len(out) > 0 {
+				nodes = out
+			}
+			return nodes, nil
+		}
+		nodes = out
+	}
+	return nodes, nil
+}
+
+func parseResults(node *yaml.RNode) (fn.Results, error) {
+	results := fn.Results{}
+	if node.IsNil() {
+		return results, nil
+	}
+	if err := node.YNode().Decode(&results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// NewFunctionConfig returns a ConfigMap function config with the data
+func NewFunctionConfig(data map[string]string) (*yaml.RNode, error) {
+	fc, err := yaml.Parse("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: function-input\n")
+	if err != nil {
+		return nil, err
+	}
+	if err := fc.PipeE(yaml.SetField("data", yaml.NewMapRNode(&data))); err != nil {
+		return nil, err
+	}
+	return fc, nil
+}
+
+func toKubeObjects(nodes []*yaml.RNode) (fn.KubeObjects, error) {
+	objs := fn.KubeObjects{}
+	for _, node := range nodes {
+		o, err := fn.ParseKubeObject([]byte(node.MustString()))
+		if err != nil {
+			return nil, err
+		}
+		objs = append(objs, o)
+	}
+	return objs, nil
+}
+
+// Compare reports the changes between the objects of a package before and
+// after a function ran, the objects are matched by their GVK, namespace and
+// name and grouped in files by their path annotation
+func Compare(before, after fn.KubeObjects) (*Report, error) {
+	r := &Report{}
+
+	beforeObjs := index(before)
+	afterObjs := index(after)
+	for _, k := range sortedKeys(afterObjs) {
+		o := afterObjs[k]
+		b, ok := beforeObjs[k]
+		switch {
+		case !ok:
+			r.Objects = append(r.Objects, ObjectChange{Type: ChangeTypeCreated, Ref: readiness.NewRef(o), Path: path(o)})
+		case strip(b) != strip(o):
+			r.Objects = append(r.Objects, ObjectChange{Type: ChangeTypeUpdated, Ref: readiness.NewRef(o), Path: path(o)})
+		}
+	}
+	for _, k := range sortedKeys(beforeObjs) {
+		if _, ok := afterObjs[k]; !ok {
+			o := beforeObjs[k]
+			r.Objects = append(r.Objects, ObjectChange{Type: ChangeTypeDeleted, Ref: readiness.NewRef(o), Path: path(o)})
+		}
+	}
+
+	beforeFiles := files(before)
+	afterFiles := files(after)
+	paths := map[string]struct{}{}
+	for p := range beforeFiles {
+		paths[p] = struct{}{}
+	}
+	for p := range afterFiles {
+		paths[p] = struct{}{}
+	}
+	for _, p := range sortedKeys(paths) {
+		if beforeFiles[p] == afterFiles[p] {
+			continue
+		}
+		diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(beforeFiles[p]),
+			B:        difflib.SplitLines(afterFiles[p]),
+			FromFile: "a/" + p,
+			ToFile:   "b/" + p,
+			Context:  3,
+		})
+		if err != nil {
+			return nil, err
+		}
+		r.Files = append(r.Files, FileDiff{Path: p, Diff: diff})
+	}
+
+	conditions, err := compareConditions(before, after)
+	if err != nil {
+		return nil, err
+	}
+	r.Conditions = conditions
+	return r, nil
+}
+
+func compareConditions(before, after fn.KubeObjects) ([]ConditionChange, error) {
+	beforeConditions, err := conditions(before)
+	if err != nil {
+		return nil, err
+	}
+	afterConditions, err := conditions(after)
+	if err != nil {
+		return nil, err
+	}
+	changes := []ConditionChange{}
+	for _, t := range sortedKeys(afterConditions) {
+		a := afterConditions[t]
+		b, ok := beforeConditions[t]
+		switch {
+		case !ok:
+			changes = append(changes, ConditionChange{Type: ChangeTypeCreated, After: &a})
+		case b != a:
+			changes = append(changes, ConditionChange{Type: ChangeTypeUpdated, Before: &b, After: &a})
+		}
+	}
+	for _, t := range sortedKeys(beforeConditions) {
+		if _, ok := afterConditions[t]; !ok {
+			b := beforeConditions[t]
+			changes = append(changes, ConditionChange{Type: ChangeTypeDeleted, Before: &b})
+		}
+	}
+	return changes, nil
+}
+
+// conditions returns the conditions of the Kptfile by type
+func conditions(objs fn.KubeObjects) (map[string]kptfilev1.Condition, error) {
+	conditions := map[string]kptfilev1.Condition{}
+	for _, o := range objs {
+		if !o.IsGVK(kptfilev1.KptfileGVK.Group, kptfilev1.KptfileGVK.Version, kptfilev1.KptfileGVK.Kind) {
+			continue
+		}
+		kf, err := kptfilev1.NewFromKubeObject(o)
+		if err != nil {
+			return nil, err
+		}
+		cs, err := kf.GetConditions()
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range cs {
+			conditions[c.Type] = c
+		}
+	}
+	return conditions, nil
+}
+
+func key(o *fn.KubeObject) string {
+	return fmt.Sprintf("%s/%s/%s/%s", o.GetAPIVersion(), o.GetKind(), o.GetNamespace(), o.GetName())
+}
+
+func index(objs fn.KubeObjects) map[string]*fn.KubeObject {
+	m := map[string]*fn.KubeObject{}
+	for _, o := range objs {
+		m[key(o)] = o
+	}
+	return m
+}
+
+func path(o *fn.KubeObject) string {
+	if p := o.GetAnnotation(kioutil.PathAnnotation); p != "" {
+		return p
+	}
+	return o.GetAnnotation(kioutil.LegacyPathAnnotation)
+}
+
+func fileIndex(o *fn.KubeObject) int {
+	i := o.GetAnnotation(kioutil.IndexAnnotation)
+	if i == "" {
+		i = o.GetAnnotation(kioutil.LegacyIndexAnnotation)
+	}
+	n, _ := strconv.Atoi(i)
+	return n
+}
+
+// files renders the objects per file in the order of their index annotation
+func files(objs fn.KubeObjects) map[string]string {
+	byPath := map[string]fn.KubeObjects{}
+	for _, o := range objs {
+		byPath[path(o)] = append(byPath[path(o)], o)
+	}
+	files := map[string]string{}
+	for p, objs := range byPath {
+		sort.SliceStable(objs, func(i, j int) bool {
+			if fileIndex(objs[i]) != fileIndex(objs[j]) {
+				return fileIndex(objs[i]) < fileIndex(objs[j])
+			}
+			return key(objs[i]) < key(objs[j])
+		})
+		docs := make([]string, 0, len(objs))
+		for _, o := range objs {
+			docs = append(docs, strip(o))
+		}
+		files[p] = strings.Join(docs, "---\n")
+	}
+	return files
+}
+
+// strip returns the yaml of the object without the annotations that locate
+// it in the package, which are not part of the file content
+func strip(o *fn.KubeObject) string {
+	node, err := yaml.Parse(o.String())
+	if err != nil {
+		return o.String()
+	}
+	keys := []string{kioutil.LegacyPathAnnotation, kioutil.LegacyIndexAnnotation, kioutil.LegacyIdAnnotation}
+	for k := range kioutil.GetInternalAnnotations(node) {
+		keys = append(keys, k)
+	}
+	for _, k := range keys {
+		if err := node.PipeE(yaml.ClearAnnotation(k)); err != nil {
+			return o.String()
+		}
+	}
+	if err := yaml.ClearEmptyAnnotations(node); err != nil {
+		return o.String()
+	}
+	return node.MustString()
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// HasChanges returns true if the function changed the package
+func (r *Report) HasChanges() bool {
+	return len(r.Files) > 0 || len(r.Objects) > 0 || len(r.Conditions) > 0
+}
+
+// Count returns the number of objects with the change type
+func (r *Report) Count(t ChangeType) int {
+	n := 0
+	for _, c := range r.Objects {
+		if c.Type == t {
+			n++
+		}
+	}
+	return n
+}
+
+// String returns the unified diffs followed by the summary of the changes
+func (r *Report) String() string {
+	var sb strings.Builder
+	for _, f := range r.Files {
+		sb.WriteString(f.Diff)
+	}
+	if len(r.Files) > 0 {
+		sb.WriteString("\n")
+	}
+	for _, t := range []ChangeType{ChangeTypeCreated, ChangeTypeUpdated, ChangeTypeDeleted} {
+		if r.Count(t) == 0 {
+			continue
+		}
+		fmt.Fprintf(&sb, "%s:\n", t)
+		for _, c := range r.Objects {
+			if c.Type == t {
+				fmt.Fprintf(&sb, "  %s (%s)\n", c.Ref.String(), c.Path)
+			}
+		}
+	}
+	if len(r.Conditions) > 0 {
+		sb.WriteString("conditions:\n")
+		for _, c := range r.Conditions {
+			switch c.Type {
+			case ChangeTypeCreated:
+				fmt.Fprintf(&sb, "  + %s: %s\n", c.After.Type, condition(c.After))
+			case ChangeTypeUpdated:
+				fmt.Fprintf(&sb, "  ~ %s: %s -> %s\n", c.After.Type, condition(c.Before), condition(c.After))
+			case ChangeTypeDeleted:
+				fmt.Fprintf(&sb, "  - %s: %s\n", c.Before.Type, condition(c.Before))
+			}
+		}
+	}
+	for _, f := range r.Results {
+		fmt.Fprintf(&sb, "results of %s:\n", f.Function)
+		for _, result := range f.Results {
+			fmt.Fprintf(&sb, "  %s\n", result.String())
+		}
+	}
+	fmt.Fprintf(&sb, "%d created, %d updated, %d deleted objects, %d changed conditions\n",
+		r.Count(ChangeTypeCreated), r.Count(ChangeTypeUpdated), r.Count(ChangeTypeDeleted), len(r.Conditions))
+	return sb.String()
+}
+
+func condition(c *kptfilev1.Condition) string {
+	s := string(c.Status)
+	if c.Message != "" {
+		s += " " + c.Message
+	}
+	if c.Reason != "" {
+		s += " (" + c.Reason + ")"
+	}
+	return s
+}
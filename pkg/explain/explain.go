@@ -0,0 +1,219 @@
+/*
+Copyright 2023 The Nephio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package explain
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/henderiw-nephio/pkg-examples/pkg/fnmeta"
+	kptfilev1 "github.com/henderiw-nephio/pkg-examples/pkg/kptfile/v1"
+	"github.com/henderiw-nephio/pkg-examples/pkg/ownergraph"
+	"github.com/henderiw-nephio/pkg-examples/pkg/readiness"
+)
+
+// FunctionRole is a function acting on the explained resource
+type FunctionRole struct {
+	Function     string
+	ResourceKind fnmeta.ResourceKind
+}
+
+// Watched is a resource watched by the function processing the explained
+// resource
+type Watched struct {
+	Resource fnmeta.Resource
+	Names    []string
+}
+
+// Explanation explains why a resource exists and what it is waiting for
+type Explanation struct {
+	Node *ownergraph.Node
+	// OwnerChain is the chain of owners, starting at the direct owner and
+	// ending at the for-object at the root of the chain
+	OwnerChain []*ownergraph.Node
+	// OwnedBy are the functions owning the resource as child of its owner
+	OwnedBy []FunctionRole
+	// ProcessedBy are the functions that have the resource as for-object, they
+	// generate a resource that is a remote condition child of its owner
+	ProcessedBy []string
+	Watches     []Watched
+	Children    []*ownergraph.Node
+	Blocking    []string
+}
+
+// Resolve returns the node of the resource reference. The reference is a
+// Kptfile condition type or <kind>/<name>.
+func Resolve(g *ownergraph.Graph, s string) (*ownergraph.Node, error) {
+	if node, ok := g.Nodes[s]; ok {
+		return node, nil
+	}
+	split := strings.Split(s, "/")
+	if len(split) == 2 {
+		found := []*ownergraph.Node{}
+		for _, node := range g.SortedNodes() {
+			if strings.EqualFold(node.Ref.Kind, split[0]) && node.Ref.Name == split[1] {
+				found = append(found, node)
+			}
+		}
+		if len(found) == 1 {
+			return found[0], nil
+		}
+		if len(found) > 1 {
+			return nil, fmt.Errorf("resource %q is ambiguous, use the condition type format", s)
+		}
+	}
+	return nil, fmt.Errorf("resource %q not found in the package or its conditions", s)
+}
+
+// Explain walks the owner chain and the conditions of the resource and
+// returns the functions involved and the conditions that block the resource
+func Explain(g *ownergraph.Graph, node *ownergraph.Node, fns []fnmeta.Description) *Explanation {
+	e := &Explanation{Node: node, Children: g.Children(node.ID())}
+
+	visited := map[string]bool{node.ID(): true}
+	for current := node; ; {
+		owners := g.Owners(current.ID())
+		if len(owners) == 0 || visited[owners[0].ID()] {
+			break
+		}
+		current = owners[0]
+		visited[current.ID()] = true
+		e.OwnerChain = append(e.OwnerChain, current)
+	}
+
+	ref := node.Ref
+	if len(e.OwnerChain) > 0 {
+		owner := e.OwnerChain[0].Ref
+		for _, f := range fnmeta.GetFor(fns, owner.APIVersion(), owner.Kind) {
+			if o := f.OwnedResource(ref.APIVersion(), ref.Kind); o != nil {
+				e.OwnedBy = append(e.OwnedBy, FunctionRole{Function: f.Name, ResourceKind: o.ResourceKind})
+			}
+		}
+	}
+	// a resource watched by several functions processing the resource is
+	// listed once
+	chain := append([]*ownergraph.Node{node}, e.OwnerChain...)
+	watched := map[fnmeta.Resource]bool{}
+	for _, f := range fnmeta.GetFor(fns, ref.APIVersion(), ref.Kind) {
+		e.ProcessedBy = append(e.ProcessedBy, f.Name)
+		for _, w := range f.Watch {
+			if watched[w] {
+				continue
+			}
+			watched[w] = true
+			e.Watches = append(e.Watches, Watched{Resource: w, Names: objectNames(g, w, chain)})
+		}
+	}
+
+	if node.Status() == kptfilev1.ConditionFalse {
+		blocking := fmt.Sprintf("own condition is false: %s", node.Condition.Message)
+		if node.Object == nil && len(e.ProcessedBy) > 0 {
+			blocking = fmt.Sprintf("%s, waiting for %s to generate the resource", blocking, strings.Join(e.ProcessedBy, ", "))
+		}
+		e.Blocking = append(e.Blocking, blocking)
+	}
+	for _, child := range e.Children {
+		if child.Status() == kptfilev1.ConditionFalse {
+			e.Blocking = append(e.Blocking, fmt.Sprintf("child %s %s: %s", child.Ref.Kind, child.Ref.Name, child.Condition.Message))
+		}
+	}
+	for _, w := range e.Watches {
+		if len(w.Names) == 0 {
+			e.Blocking = append(e.Blocking, fmt.Sprintf("watched %s is missing in the package", w.Resource.Kind))
+		}
+	}
+	return e
+}
+
+// objectNames returns the names of the watched objects relevant to the
+// resource with the owner chain: the objects in the chain, owned by a node of
+// the chain or named after the resource. Without relevant objects the objects
+// of the package without an owner are returned, such as the ClusterContext,
+// while the children of other owners are left out.
+func objectNames(g *ownergraph.Graph, r fnmeta.Resource, chain []*ownergraph.Node) []string {
+	inChain := map[string]bool{}
+	for _, n := range chain {
+		inChain[n.ID()] = true
+	}
+	related := []string{}
+	unowned := []string{}
+	for _, node := range g.SortedNodes() {
+		if node.Object == nil || !r.Matches(node.Ref.APIVersion(), node.Ref.Kind) {
+			continue
+		}
+		owners := g.Owners(node.ID())
+		isRelated := inChain[node.ID()] || node.Ref.Name == chain[0].Ref.Name
+		for _, owner := range owners {
+			isRelated = isRelated || inChain[owner.ID()]
+		}
+		switch {
+		case isRelated:
+			related = append(related, node.Ref.Name)
+		case len(owners) == 0:
+			unowned = append(unowned, node.Ref.Name)
+		}
+	}
+	if len(related) > 0 {
+		return related
+	}
+	return unowned
+}
+
+func describe(ref readiness.Ref) string {
+	return fmt.Sprintf("%s %s (%s)", ref.Kind, ref.Name, ref.APIVersion())
+}
+
+// String returns the explanation in a human readable format
+func (r *Explanation) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s\n", describe(r.Node.Ref))
+	fmt.Fprintf(&sb, "  in package: %t\n", r.Node.Object != nil)
+	if r.Node.Condition != nil {
+		fmt.Fprintf(&sb, "  condition: %s - %s\n", r.Node.Condition.Status, r.Node.Condition.Message)
+	} else {
+		sb.WriteString("  condition: none\n")
+	}
+	if len(r.OwnerChain) == 0 {
+		sb.WriteString("  owner: none, the resource is a for-object or part of the blueprint\n")
+	}
+	for i, owner := range r.OwnerChain {
+		fmt.Fprintf(&sb, "  %sowner: %s\n", strings.Repeat("  ", i), describe(owner.Ref))
+	}
+	for _, o := range r.OwnedBy {
+		fmt.Fprintf(&sb, "  owned by function: %s (%s)\n", o.Function, o.ResourceKind)
+	}
+	for _, f := range r.ProcessedBy {
+		fmt.Fprintf(&sb, "  processed by function: %s\n", f)
+	}
+	for _, w := range r.Watches {
+		names := "missing"
+		if len(w.Names) > 0 {
+			names = strings.Join(w.Names, ", ")
+		}
+		fmt.Fprintf(&sb, "  watches %s: %s\n", w.Resource.Kind, names)
+	}
+	for _, child := range r.Children {
+		fmt.Fprintf(&sb, "  child: %s: %s\n", describe(child.Ref), child.Status())
+	}
+	if len(r.Blocking) == 0 {
+		sb.WriteString("  blocking: nothing\n")
+	}
+	for _, b := range r.Blocking {
+		fmt.Fprintf(&sb, "  blocking: %s\n", b)
+	}
+	return sb.String()
+}
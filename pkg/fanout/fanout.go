@@ -0,0 +1,317 @@
+/*
+Copyright 2023 The Nephio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fanout
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt-functions-sdk/go/fn"
+	"github.com/henderiw-nephio/pkg-examples/pkg/localconfig"
+	"github.com/henderiw-nephio/pkg-examples/pkg/pkgcontext"
+	"sigs.k8s.io/kustomize/kyaml/fn/runtime/exec"
+	"sigs.k8s.io/kustomize/kyaml/fn/runtime/runtimeutil"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/kio/kioutil"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+const (
+	kptfileName            = "Kptfile"
+	clusterContextFileName = "cluster_context.yaml"
+)
+
+var (
+	clusterContextGVK = fn.IsGVK("infra.nephio.org", "v1alpha1", "ClusterContext")
+	ipAllocationGVK   = fn.IsGVK("ipam.alloc.nephio.org", "v1alpha1", "IPAllocation")
+	vlanAllocationGVK = fn.IsGVK("vlan.alloc.nephio.org", "v1alpha1", "VLANAllocation")
+)
+
+// Config of the fan-out of a blueprint package to a set of clusters
+type Config struct {
+	// Blueprint is the directory of the blueprint package
+	Blueprint string
+	// Output is the directory the specialized packages are written to, every
+	// package is written to a subdirectory named after its cluster context
+	Output string
+	// Functions is the function chain run on every package, every function is
+	// an executable with its arguments that reads and writes a ResourceList
+	Functions [][]string
+}
+
+// Summary of the fan-out
+type Summary struct {
+	Packages  []PackageResult
+	Conflicts []Conflict
+}
+
+// PackageResult is the result of the specialization of a package
+type PackageResult struct {
+	Name string
+	Dir  string
+	Err  error
+}
+
+// Conflict is a resource allocated to more than one owner, such as an ip
+// address in a network instance or a vlan in a vlan database
+type Conflict struct {
+	Resource string
+	Owners   []string
+}
+
+// HasErrors returns true if a package failed or a conflict was found
+func (r *Summary) HasErrors() bool {
+	for _, p := range r.Packages {
+		if p.Err != nil {
+			return true
+		}
+	}
+	return len(r.Conflicts) > 0
+}
+
+func (r *Summary) String() string {
+	var sb strings.Builder
+	for _, p := range r.Packages {
+		if p.Err != nil {
+			fmt.Fprintf(&sb, "package %s: failed: %v\n", p.Name, p.Err)
+			continue
+		}
+		fmt.Fprintf(&sb, "package %s: written to %s\n", p.Name, p.Dir)
+	}
+	for _, c := range r.Conflicts {
+		fmt.Fprintf(&sb, "conflict %s: allocated to %s\n", c.Resource, strings.Join(c.Owners, ", "))
+	}
+	fmt.Fprintf(&sb, "%d package(s), %d conflict(s)\n", len(r.Packages), len(r.Conflicts))
+	return sb.String()
+}
+
+// ReadClusterContexts returns the cluster contexts in the yaml files of the
+// directory
+func ReadClusterContexts(dir string) (fn.KubeObjects, error) {
+	nodes, err := (&kio.LocalPackageReader{PackagePath: dir, OmitReaderAnnotations: true}).Read()
+	if err != nil {
+		return nil, err
+	}
+	objs := fn.KubeObjects{}
+	for _, node := range nodes {
+		o, err := fn.ParseKubeObject([]byte(node.MustString()))
+		if err != nil {
+			return nil, err
+		}
+		if clusterContextGVK(o) {
+			objs = append(objs, o)
+		}
+	}
+	sort.SliceStable(objs, func(i, j int) bool {
+		return objs[i].GetName() < objs[j].GetName()
+	})
+	return objs, nil
+}
+
+// Run specializes the blueprint for every cluster context and returns the
+// summary of the fan-out. The allocations of all packages are checked for
+// conflicts as the packages share the same ipam and vlan backends.
+func Run(cfg *Config, clusterContexts fn.KubeObjects) (*Summary, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	summary := &Summary{}
+	allocations := map[string][]string{}
+	for _, clusterContext := range clusterContexts {
+		name := clusterContext.GetName()
+		dir := filepath.Join(cfg.Output, name)
+		objs, err := specialize(cfg, wd, dir, name, clusterContext)
+		summary.Packages = append(summary.Packages, PackageResult{Name: name, Dir: dir, Err: err})
+		if err != nil {
+			continue
+		}
+		for _, o := range objs {
+			resource, ok, err := getAllocatedResource(o)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				allocations[resource] = append(allocations[resource], fmt.Sprintf("%s/%s.%s", name, o.GetKind(), o.GetName()))
+			}
+		}
+	}
+
+	for resource, owners := range allocations {
+		if len(owners) > 1 {
+			sort.Strings(owners)
+			summary.Conflicts = append(summary.Conflicts, Conflict{Resource: resource, Owners: owners})
+		}
+	}
+	sort.Slice(summary.Conflicts, func(i, j int) bool {
+		return summary.Conflicts[i].Resource < summary.Conflicts[j].Resource
+	})
+	return summary, nil
+}
+
+// specialize writes the package specialized for the cluster context to the
+// directory and returns the objects in the package
+func specialize(cfg *Config, wd, dir, name string, clusterContext *fn.KubeObject) (fn.KubeObjects, error) {
+	filters := []kio.Filter{
+		kio.FilterFunc(func(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
+			return injectClusterContext(nodes, name, clusterContext)
+		}),
+	}
+	for _, f := range cfg.Functions {
+		if len(f) == 0 {
+			continue
+		}
+		filters = append(filters, &exec.Filter{
+			Path:           f[0],
+			Args:           f[1:],
+			WorkingDir:     wd,
+			FunctionFilter: runtimeutil.FunctionFilter{GlobalScope: true},
+		})
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	var pb kio.PackageBuffer
+	if err := (kio.Pipeline{
+		Inputs: []kio.Reader{&kio.LocalPackageReader{
+			PackagePath:    cfg.Blueprint,
+			MatchFilesGlob: append(kio.MatchAll, kptfileName),
+		}},
+		Filters: filters,
+		Outputs: []kio.Writer{
+			&kio.LocalPackageWriter{PackagePath: dir},
+			&pb,
+		},
+	}).Execute(); err != nil {
+		return nil, err
+	}
+	if err := copyFiles(cfg.Blueprint, dir); err != nil {
+		return nil, err
+	}
+
+	objs := fn.KubeObjects{}
+	for _, node := range pb.Nodes {
+		o, err := fn.ParseKubeObject([]byte(node.MustString()))
+		if err != nil {
+			return nil, err
+		}
+		objs = append(objs, o)
+	}
+	return objs, nil
+}
+
+// injectClusterContext replaces the cluster context of the blueprint with the
+// cluster context of the target cluster and names the package after it
+func injectClusterContext(nodes []*yaml.RNode, name string, clusterContext *fn.KubeObject) ([]*yaml.RNode, error) {
+	result := make([]*yaml.RNode, 0, len(nodes)+1)
+	for _, node := range nodes {
+		switch {
+		case node.GetKind() == "ClusterContext" && node.GetApiVersion() == "infra.nephio.org/v1alpha1":
+			continue
+		case node.GetKind() == kptfileName:
+			if err := node.SetName(name); err != nil {
+				return nil, err
+			}
+		case node.GetKind() == "ConfigMap" && node.GetName() == pkgcontext.ConfigMapName:
+			node.SetDataMap(mergeMap(node.GetDataMap(), map[string]string{"name": name}))
+		}
+		result = append(result, node)
+	}
+
+	node, err := yaml.Parse(clusterContext.String())
+	if err != nil {
+		return nil, err
+	}
+	annotations := mergeMap(node.GetAnnotations(), nil)
+	annotations[localconfig.Annotation] = "true"
+	annotations[kioutil.PathAnnotation] = clusterContextFileName
+	annotations[kioutil.LegacyPathAnnotation] = clusterContextFileName
+	annotations[kioutil.IndexAnnotation] = "0"
+	annotations[kioutil.LegacyIndexAnnotation] = "0"
+	if err := node.SetAnnotations(annotations); err != nil {
+		return nil, err
+	}
+	return append(result, node), nil
+}
+
+// getAllocatedResource returns the resource allocated by an ip or vlan
+// allocation: the address in the network instance or the vlan in the
+// vlan database
+func getAllocatedResource(o *fn.KubeObject) (string, bool, error) {
+	switch {
+	case ipAllocationGVK(o):
+		ni, _, err := o.NestedString("spec", "networkInstance", "name")
+		if err != nil {
+			return "", false, err
+		}
+		prefix, _, err := o.NestedString("status", "prefix")
+		if err != nil || prefix == "" {
+			return "", false, err
+		}
+		address, _, _ := strings.Cut(prefix, "/")
+		return fmt.Sprintf("ip %s in network instance %s", address, ni), true, nil
+	case vlanAllocationGVK(o):
+		db, _, err := o.NestedString("spec", "vlanDatabase", "name")
+		if err != nil {
+			return "", false, err
+		}
+		vlanID, ok, err := o.NestedInt("status", "vlanID")
+		if err != nil || !ok {
+			return "", false, err
+		}
+		return fmt.Sprintf("vlan %d in vlan database %s", vlanID, db), true, nil
+	}
+	return "", false, nil
+}
+
+// copyFiles copies the files of the blueprint that are not resources, such
+// as the README
+func copyFiles(src, dst string) error {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || entry.Name() == kptfileName || ext == ".yaml" || ext == ".yml" {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(src, entry.Name()))
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dst, entry.Name()), b, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func mergeMap(m1, m2 map[string]string) map[string]string {
+	m := map[string]string{}
+	for k, v := range m1 {
+		m[k] = v
+	}
+	for k, v := range m2 {
+		m[k] = v
+	}
+	return m
+}
@@ -0,0 +1,267 @@
+/*
+Copyright 2023 The Nephio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fnmeta
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// DescribeArg is the argument that makes a function binary print its
+	// description as json instead of processing a resource list
+	DescribeArg = "describe"
+)
+
+// ResourceKind distinguishes the child resources of a function, the values
+// match the condkptsdk resource kinds
+type ResourceKind string
+
+const (
+	// ChildRemoteCondition is a child for which the function only creates a
+	// condition, the resource is generated by a downstream function
+	ChildRemoteCondition ResourceKind = "remoteCondition"
+	// ChildRemote is a child for which the function creates the condition
+	// and the resource
+	ChildRemote ResourceKind = "remote"
+	// ChildLocal is a child for which no condition is created
+	ChildLocal ResourceKind = "local"
+)
+
+// Resource identifies a resource kind in the description of a function
+type Resource struct {
+	APIVersion   string       `json:"apiVersion,omitempty"`
+	Kind         string       `json:"kind,omitempty"`
+	ResourceKind ResourceKind `json:"resourceKind,omitempty"`
+}
+
+// NewResource returns the resource of the GroupVersionKind
+func NewResource(gvk schema.GroupVersionKind) Resource {
+	return Resource{APIVersion: gvk.GroupVersion().Identifier(), Kind: gvk.Kind}
+}
+
+// Matches returns true if the resource has the apiVersion and kind, the empty
+// for-object of a function acting on the whole package matches nothing
+func (r Resource) Matches(apiVersion, kind string) bool {
+	return r.Kind != "" && r.APIVersion == apiVersion && r.Kind == kind
+}
+
+// Description describes the resources a function acts on: the for-object it
+// generates children for, the children it owns and the resources it watches.
+// A function acting on the whole package, like a validator, has no for-object.
+type Description struct {
+	Name  string     `json:"name"`
+	Image string     `json:"image,omitempty"`
+	For   Resource   `json:"for"`
+	Owns  []Resource `json:"owns,omitempty"`
+	Watch []Resource `json:"watch,omitempty"`
+	// Validator is set for the functions that only validate the package,
+	// they belong in the validators of the pipeline
+	Validator bool `json:"validator,omitempty"`
+}
+
+// OwnedResource returns the owned resource with the apiVersion and kind, nil is
+// returned if the function does not own the resource
+func (r *Description) OwnedResource(apiVersion, kind string) *Resource {
+	for _, o := range r.Owns {
+		if o.Matches(apiVersion, kind) {
+			o := o
+			return &o
+		}
+	}
+	return nil
+}
+
+// Sort sorts the owned and watched resources of the description to keep the
+// description stable, the resources are kept in maps in condkptsdk
+func (r *Description) Sort() {
+	less := func(s []Resource) func(i, j int) bool {
+		return func(i, j int) bool {
+			if s[i].APIVersion != s[j].APIVersion {
+				return s[i].APIVersion < s[j].APIVersion
+			}
+			return s[i].Kind < s[j].Kind
+		}
+	}
+	sort.Slice(r.Owns, less(r.Owns))
+	sort.Slice(r.Watch, less(r.Watch))
+}
+
+// NewDescription returns the description of a function from the For, Owns
+// and Watch of its condkptsdk config
+func NewDescription[K ~string, W any](name string, forRef corev1.ObjectReference, owns map[corev1.ObjectReference]K, watch map[corev1.ObjectReference]W) Description {
+	d := Description{
+		Name:  name,
+		For:   Resource{APIVersion: forRef.APIVersion, Kind: forRef.Kind},
+		Owns:  []Resource{},
+		Watch: []Resource{},
+	}
+	for ref, kind := range owns {
+		d.Owns = append(d.Owns, Resource{APIVersion: ref.APIVersion, Kind: ref.Kind, ResourceKind: ResourceKind(kind)})
+	}
+	for ref := range watch {
+		d.Watch = append(d.Watch, Resource{APIVersion: ref.APIVersion, Kind: ref.Kind})
+	}
+	d.Sort()
+	return d
+}
+
+// Print writes the description as json
+func Print(w io.Writer, d Description) error {
+	b, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
+
+// Parse returns the descriptions in the json, the json is a single
+// description or a list of descriptions
+func Parse(b []byte) ([]Description, error) {
+	descs := []Description{}
+	if strings.HasPrefix(strings.TrimSpace(string(b)), "[") {
+		if err := json.Unmarshal(b, &descs); err != nil {
+			return nil, err
+		}
+		return descs, nil
+	}
+	d := Description{}
+	if err := json.Unmarshal(b, &d); err != nil {
+		return nil, err
+	}
+	return append(descs, d), nil
+}
+
+// dependsOn returns true if the function b runs after the function a: b
+// processes or watches a kind that a owns, or b watches the for-object of a
+func dependsOn(b, a Description) bool {
+	for _, o := range a.Owns {
+		if b.For.Matches(o.APIVersion, o.Kind) {
+			return true
+		}
+		for _, w := range b.Watch {
+			if w.Matches(o.APIVersion, o.Kind) {
+				return true
+			}
+		}
+	}
+	for _, w := range b.Watch {
+		if w.Matches(a.For.APIVersion, a.For.Kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// Order returns the functions ordered such that upstream functions run before
+// the downstream functions depending on them. Functions without a dependency
+// between them are ordered by name to keep the order stable. An error is
+// returned if the dependencies have a cycle.
+func Order(fns []Description) ([]Description, error) {
+	sorted := make([]Description, len(fns))
+	copy(sorted, fns)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	inDegree := make([]int, len(sorted))
+	for i := range sorted {
+		for j := range sorted {
+			if i != j && dependsOn(sorted[i], sorted[j]) {
+				inDegree[i]++
+			}
+		}
+	}
+	ordered := []Description{}
+	done := make([]bool, len(sorted))
+	for len(ordered) < len(sorted) {
+		next := -1
+		for i := range sorted {
+			if !done[i] && inDegree[i] == 0 {
+				next = i
+				break
+			}
+		}
+		if next == -1 {
+			cycle := []string{}
+			for i := range sorted {
+				if !done[i] {
+					cycle = append(cycle, sorted[i].Name)
+				}
+			}
+			return nil, fmt.Errorf("cycle in the dependencies of the functions: %s", strings.Join(cycle, ", "))
+		}
+		done[next] = true
+		ordered = append(ordered, sorted[next])
+		for i := range sorted {
+			if !done[i] && dependsOn(sorted[i], sorted[next]) {
+				inDegree[i]--
+			}
+		}
+	}
+	return ordered, nil
+}
+
+//go:generate sh -c "cd ../../allfn && go run . describe > ../pkg/fnmeta/functions.json"
+
+//go:embed functions.json
+var functions []byte
+
+// Functions are the descriptions of the functions in this repository for the
+// tools that cannot import the fn modules. They are generated from the
+// describe mode of the functions registered in allfn, the registry test in
+// allfn checks that they are up to date.
+var Functions = mustParse(functions)
+
+func mustParse(b []byte) []Description {
+	descs, err := Parse(b)
+	if err != nil {
+		panic(fmt.Sprintf("invalid function descriptions: %v", err))
+	}
+	return descs
+}
+
+// GetFor returns the functions with the apiVersion and kind as for-object
+func GetFor(fns []Description, apiVersion, kind string) []Description {
+	found := []Description{}
+	for _, f := range fns {
+		if f.For.Matches(apiVersion, kind) {
+			found = append(found, f)
+		}
+	}
+	return found
+}
+
+// GetOwners returns the functions that own the apiVersion and kind as child
+// resource
+func GetOwners(fns []Description, apiVersion, kind string) []Description {
+	found := []Description{}
+	for _, f := range fns {
+		if f.OwnedResource(apiVersion, kind) != nil {
+			found = append(found, f)
+		}
+	}
+	return found
+}
@@ -0,0 +1,256 @@
+/*
+Copyright 2023 The Nephio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fnserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/GoogleContainerTools/kpt-functions-sdk/go/fn"
+)
+
+const (
+	// FunctionsPath lists the functions on GET, a function is run by a POST of
+	// a ResourceList to FunctionsPath/<name>
+	FunctionsPath = "/v1/functions"
+	// HealthPath returns ok as long as the server is serving
+	HealthPath = "/healthz"
+
+	// ErrorHeader holds the error of a function that failed, the body of the
+	// response still holds the ResourceList with the results of the function
+	ErrorHeader = "X-Function-Error"
+
+	contentType = "application/yaml"
+
+	defaultMaxConcurrency = 4
+	defaultTimeout        = 30 * time.Second
+	defaultMaxRequestSize = 32 << 20
+)
+
+// Config of the function server
+type Config struct {
+	// Functions are the functions served by name
+	Functions map[string]fn.ResourceListProcessorFunc
+	// MaxConcurrency is the number of functions run at the same time, other
+	// requests wait for a free slot until their timeout expires
+	MaxConcurrency int
+	// Timeout of a request, including the time waiting for a free slot
+	Timeout time.Duration
+	// MaxRequestSize is the maximum size of a ResourceList in bytes
+	MaxRequestSize int64
+}
+
+// Server runs functions on the ResourceLists posted to it
+type Server struct {
+	cfg   Config
+	slots chan struct{}
+	mux   *http.ServeMux
+}
+
+// New returns a server for the functions in the config, the limits that are
+// not set get a default
+func New(cfg Config) *Server {
+	if cfg.MaxConcurrency <= 0 {
+		cfg.MaxConcurrency = defaultMaxConcurrency
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.MaxRequestSize <= 0 {
+		cfg.MaxRequestSize = defaultMaxRequestSize
+	}
+	s := &Server{
+		cfg:   cfg,
+		slots: make(chan struct{}, cfg.MaxConcurrency),
+		mux:   http.NewServeMux(),
+	}
+	s.mux.HandleFunc(HealthPath, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	s.mux.HandleFunc(FunctionsPath, s.list)
+	s.mux.HandleFunc(FunctionsPath+"/", s.run)
+	return s
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Names returns the sorted names of the served functions
+func (s *Server) Names() []string {
+	names := make([]string, 0, len(s.cfg.Functions))
+	for name := range s.cfg.Functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.Names()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+type result struct {
+	out []byte
+	err error
+	// panicked is set when the function panicked, which must not take down
+	// the server with the other functions running
+	panicked bool
+}
+
+func (s *Server) run(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, FunctionsPath+"/")
+	p, ok := s.cfg.Functions[name]
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown function %q", name), http.StatusNotFound)
+		return
+	}
+	in, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.cfg.MaxRequestSize))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("cannot read ResourceList: %s", err.Error()), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.cfg.Timeout)
+	defer cancel()
+
+	select {
+	case s.slots <- struct{}{}:
+	case <-ctx.Done():
+		http.Error(w, fmt.Sprintf("no free slot to run function %q: %s", name, ctx.Err().Error()), http.StatusServiceUnavailable)
+		return
+	}
+
+	// a function cannot be interrupted, after a timeout it keeps its slot
+	// until it returns such that the number of running functions stays
+	// within the limit
+	done := make(chan result, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- result{err: fmt.Errorf("function %q panicked: %v", name, r), panicked: true}
+			}
+			<-s.slots
+		}()
+		out, err := fn.Run(p, in)
+		done <- result{out: out, err: err}
+	}()
+
+	select {
+	case res := <-done:
+		if res.panicked {
+			http.Error(w, res.err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if res.err != nil && len(res.out) == 0 {
+			http.Error(w, res.err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		status := http.StatusOK
+		if res.err != nil {
+			w.Header().Set(ErrorHeader, strings.ReplaceAll(res.err.Error(), "\n", " "))
+			status = http.StatusUnprocessableEntity
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write(res.out)
+	case <-ctx.Done():
+		http.Error(w, fmt.Sprintf("function %q did not return: %s", name, ctx.Err().Error()), http.StatusGatewayTimeout)
+	}
+}
+
+// Client runs functions on a function server
+type Client struct {
+	// URL of the server, e.g. http://localhost:9445
+	URL        string
+	HTTPClient *http.Client
+}
+
+// NewClient returns a client of the server at the url
+func NewClient(url string) *Client {
+	return &Client{URL: strings.TrimSuffix(url, "/"), HTTPClient: http.DefaultClient}
+}
+
+// List returns the names of the functions served
+func (c *Client) List(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.URL+FunctionsPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("list functions: %s: %s", resp.Status, strings.TrimSpace(string(b)))
+	}
+	names := []string{}
+	if err := json.NewDecoder(resp.Body).Decode(&names); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// Run runs the function on the ResourceList in yaml format, like fn.Run the
+// output ResourceList is returned together with the error of a function that
+// failed
+func (c *Client) Run(ctx context.Context, name string, input []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL+FunctionsPath+"/"+name, bytes.NewReader(input))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	out, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return out, nil
+	case http.StatusUnprocessableEntity:
+		return out, errors.New(resp.Header.Get(ErrorHeader))
+	default:
+		return nil, fmt.Errorf("function %s: %s: %s", name, resp.Status, strings.TrimSpace(string(out)))
+	}
+}
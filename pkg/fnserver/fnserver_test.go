@@ -0,0 +1,188 @@
+/*
+Copyright 2023 The Nephio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fnserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GoogleContainerTools/kpt-functions-sdk/go/fn"
+)
+
+const testInput = `apiVersion: config.kubernetes.io/v1
+kind: ResourceList
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: test
+`
+
+func newTestServer(t *testing.T, cfg Config) *Client {
+	t.Helper()
+	ts := httptest.NewServer(New(cfg))
+	t.Cleanup(ts.Close)
+	return NewClient(ts.URL)
+}
+
+func ok(rl *fn.ResourceList) (bool, error) {
+	return true, nil
+}
+
+// post returns the status code of a ResourceList posted to the function
+func post(t *testing.T, c *Client, name, input string) int {
+	t.Helper()
+	status, err := postStatus(c, name, input)
+	if err != nil {
+		t.Fatalf("cannot post to function %s: %v", name, err)
+	}
+	return status
+}
+
+func postStatus(c *Client, name, input string) (int, error) {
+	resp, err := c.HTTPClient.Post(c.URL+FunctionsPath+"/"+name, contentType, strings.NewReader(input))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+func TestRun(t *testing.T) {
+	c := newTestServer(t, Config{Functions: map[string]fn.ResourceListProcessorFunc{"ok": ok}})
+	out, err := c.Run(context.Background(), "ok", []byte(testInput))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(out), "name: test") {
+		t.Errorf("expected the items of the input in the output, got:\n%s", out)
+	}
+}
+
+func TestFunctionError(t *testing.T) {
+	c := newTestServer(t, Config{Functions: map[string]fn.ResourceListProcessorFunc{
+		"fail": func(rl *fn.ResourceList) (bool, error) {
+			rl.Results.Errorf("no interfaces")
+			return false, nil
+		},
+	}})
+	out, err := c.Run(context.Background(), "fail", []byte(testInput))
+	if err == nil {
+		t.Fatalf("expected the error of the function")
+	}
+	if !strings.Contains(string(out), "no interfaces") {
+		t.Errorf("expected the results of the function in the output, got:\n%s", out)
+	}
+	if got := post(t, c, "fail", "not a resource list"); got != http.StatusBadRequest {
+		t.Errorf("expected status %d for an invalid ResourceList, got %d", http.StatusBadRequest, got)
+	}
+}
+
+func TestUnknownFunction(t *testing.T) {
+	c := newTestServer(t, Config{Functions: map[string]fn.ResourceListProcessorFunc{"ok": ok}})
+	if got := post(t, c, "unknown", testInput); got != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, got)
+	}
+	resp, err := c.HTTPClient.Get(c.URL + FunctionsPath + "/ok")
+	if err != nil {
+		t.Fatalf("cannot get function: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("expected status %d, got %d", http.StatusMethodNotAllowed, resp.StatusCode)
+	}
+	names, err := c.List(context.Background())
+	if err != nil {
+		t.Fatalf("cannot list functions: %v", err)
+	}
+	if len(names) != 1 || names[0] != "ok" {
+		t.Errorf("expected functions [ok], got %v", names)
+	}
+}
+
+func TestPanic(t *testing.T) {
+	c := newTestServer(t, Config{
+		Functions: map[string]fn.ResourceListProcessorFunc{
+			"ok": ok,
+			"panic": func(rl *fn.ResourceList) (bool, error) {
+				panic("nil interface")
+			},
+		},
+		MaxConcurrency: 1,
+	})
+	if got := post(t, c, "panic", testInput); got != http.StatusInternalServerError {
+		t.Errorf("expected status %d, got %d", http.StatusInternalServerError, got)
+	}
+	// the slot of the function that panicked is released
+	if got := post(t, c, "ok", testInput); got != http.StatusOK {
+		t.Errorf("expected status %d after a panic, got %d", http.StatusOK, got)
+	}
+}
+
+// TestTimeouts checks that a function that does not return within the timeout
+// gets a gateway timeout and keeps its slot until it returns, such that a
+// request beyond the concurrency limit is unavailable
+func TestTimeouts(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	c := newTestServer(t, Config{
+		Functions: map[string]fn.ResourceListProcessorFunc{
+			"ok": ok,
+			"block": func(rl *fn.ResourceList) (bool, error) {
+				close(started)
+				<-release
+				return true, nil
+			},
+		},
+		MaxConcurrency: 1,
+		Timeout:        100 * time.Millisecond,
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	var blocked int
+	var blockedErr error
+	go func() {
+		defer wg.Done()
+		blocked, blockedErr = postStatus(c, "block", testInput)
+	}()
+	<-started
+	if got := post(t, c, "ok", testInput); got != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d beyond the concurrency limit, got %d", http.StatusServiceUnavailable, got)
+	}
+	wg.Wait()
+	if blockedErr != nil {
+		t.Fatalf("cannot post to function block: %v", blockedErr)
+	}
+	if blocked != http.StatusGatewayTimeout {
+		t.Errorf("expected status %d for a function that did not return, got %d", http.StatusGatewayTimeout, blocked)
+	}
+
+	// the request that timed out still holds the slot
+	if got := post(t, c, "ok", testInput); got != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d while the function is running, got %d", http.StatusServiceUnavailable, got)
+	}
+	close(release)
+	if got := post(t, c, "ok", testInput); got != http.StatusOK {
+		t.Errorf("expected status %d once the function returned, got %d", http.StatusOK, got)
+	}
+}
@@ -0,0 +1,348 @@
+/*
+Copyright 2023 The Nephio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"github.com/GoogleContainerTools/kpt-functions-sdk/go/fn"
+	"github.com/henderiw-nephio/pkg-examples/pkg/kubeobject"
+	"sigs.k8s.io/kustomize/kyaml/kio/kioutil"
+)
+
+var (
+	InfoField       = []string{"info"}
+	PipelineField   = []string{"pipeline"}
+	InventoryField  = []string{"inventory"}
+	ConditionsField = []string{"status", "conditions"}
+)
+
+// NewFromKubeObject creates a new parser interface
+// It expects a *fn.KubeObject as input representing the serialized yaml file
+func NewFromKubeObject(o *fn.KubeObject) (*Kptfile, error) {
+	r, err := kubeobject.NewFromKubeObject[*KptFile](o)
+	if err != nil {
+		return nil, err
+	}
+	return &Kptfile{*r}, nil
+}
+
+// NewFromYAML creates a new parser interface
+// It expects a raw byte slice as input representing the serialized yaml file
+func NewFromYAML(b []byte) (*Kptfile, error) {
+	r, err := kubeobject.NewFromYaml[*KptFile](b)
+	if err != nil {
+		return nil, err
+	}
+	return &Kptfile{*r}, nil
+}
+
+// NewFromGoStruct creates a new parser interface
+// It expects a go struct representing the Kptfile
+func NewFromGoStruct(x *KptFile) (*Kptfile, error) {
+	r, err := kubeobject.NewFromGoStruct[*KptFile](x)
+	if err != nil {
+		return nil, err
+	}
+	return &Kptfile{*r}, nil
+}
+
+// Kptfile provides typed access to the Kptfile of a package. The setters
+// only change the field they own and keep the comments and the order of the
+// fields in the rest of the Kptfile.
+type Kptfile struct {
+	kubeobject.KubeObjectExt[*KptFile]
+}
+
+// GetInfo returns the package info, nil is returned if the Kptfile has no info
+func (r *Kptfile) GetInfo() (*PackageInfo, error) {
+	kf, err := r.GetGoStruct()
+	if err != nil {
+		return nil, err
+	}
+	return kf.Info, nil
+}
+
+// SetInfo sets the package info
+func (r *Kptfile) SetInfo(info PackageInfo) error {
+	return r.SetNestedFieldKeepFormatting(info, InfoField...)
+}
+
+// GetPipeline returns the pipeline, an empty pipeline is returned if the
+// Kptfile has no pipeline
+func (r *Kptfile) GetPipeline() (*Pipeline, error) {
+	kf, err := r.GetGoStruct()
+	if err != nil {
+		return nil, err
+	}
+	if kf.Pipeline == nil {
+		return &Pipeline{}, nil
+	}
+	return kf.Pipeline, nil
+}
+
+// SetPipeline sets the pipeline
+func (r *Kptfile) SetPipeline(pipeline Pipeline) error {
+	return r.SetNestedFieldKeepFormatting(pipeline, PipelineField...)
+}
+
+// GetMutators returns the mutators in the pipeline
+func (r *Kptfile) GetMutators() ([]Function, error) {
+	pipeline, err := r.GetPipeline()
+	if err != nil {
+		return nil, err
+	}
+	return pipeline.Mutators, nil
+}
+
+// GetValidators returns the validators in the pipeline
+func (r *Kptfile) GetValidators() ([]Function, error) {
+	pipeline, err := r.GetPipeline()
+	if err != nil {
+		return nil, err
+	}
+	return pipeline.Validators, nil
+}
+
+// SetMutator sets the mutator in the pipeline. A mutator with the same name,
+// or with the same image when the mutator has no name, is replaced, otherwise
+// the mutator is appended to the pipeline.
+func (r *Kptfile) SetMutator(f Function) error {
+	pipeline, err := r.GetPipeline()
+	if err != nil {
+		return err
+	}
+	pipeline.Mutators = setFunction(pipeline.Mutators, f)
+	return r.SetPipeline(*pipeline)
+}
+
+// SetValidator sets the validator in the pipeline, see SetMutator
+func (r *Kptfile) SetValidator(f Function) error {
+	pipeline, err := r.GetPipeline()
+	if err != nil {
+		return err
+	}
+	pipeline.Validators = setFunction(pipeline.Validators, f)
+	return r.SetPipeline(*pipeline)
+}
+
+// DeleteMutator deletes the mutators with the name or image from the pipeline
+func (r *Kptfile) DeleteMutator(nameOrImage string) error {
+	pipeline, err := r.GetPipeline()
+	if err != nil {
+		return err
+	}
+	pipeline.Mutators = deleteFunction(pipeline.Mutators, nameOrImage)
+	return r.SetPipeline(*pipeline)
+}
+
+// DeleteValidator deletes the validators with the name or image from the
+// pipeline
+func (r *Kptfile) DeleteValidator(nameOrImage string) error {
+	pipeline, err := r.GetPipeline()
+	if err != nil {
+		return err
+	}
+	pipeline.Validators = deleteFunction(pipeline.Validators, nameOrImage)
+	return r.SetPipeline(*pipeline)
+}
+
+func (r Function) key() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	if r.Image != "" {
+		return r.Image
+	}
+	return r.Exec
+}
+
+func setFunction(fns []Function, f Function) []Function {
+	for i, existing := range fns {
+		if existing.key() == f.key() {
+			fns[i] = f
+			return fns
+		}
+	}
+	return append(fns, f)
+}
+
+func deleteFunction(fns []Function, nameOrImage string) []Function {
+	newFns := []Function{}
+	for _, f := range fns {
+		if f.Name == nameOrImage || f.Image == nameOrImage || f.Exec == nameOrImage {
+			continue
+		}
+		newFns = append(newFns, f)
+	}
+	return newFns
+}
+
+// GetInventory returns the inventory, nil is returned if the Kptfile has no
+// inventory
+func (r *Kptfile) GetInventory() (*Inventory, error) {
+	kf, err := r.GetGoStruct()
+	if err != nil {
+		return nil, err
+	}
+	return kf.Inventory, nil
+}
+
+// SetInventory sets the inventory
+func (r *Kptfile) SetInventory(inventory Inventory) error {
+	return r.SetNestedFieldKeepFormatting(inventory, InventoryField...)
+}
+
+// GetConditions returns the conditions in the status of the Kptfile
+func (r *Kptfile) GetConditions() ([]Condition, error) {
+	kf, err := r.GetGoStruct()
+	if err != nil {
+		return nil, err
+	}
+	if kf.Status == nil {
+		return []Condition{}, nil
+	}
+	return kf.Status.Conditions, nil
+}
+
+// GetCondition returns the condition with the type, nil is returned if the
+// condition does not exist
+func (r *Kptfile) GetCondition(conditionType string) (*Condition, error) {
+	conditions, err := r.GetConditions()
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range conditions {
+		if c.Type == conditionType {
+			c := c
+			return &c, nil
+		}
+	}
+	return nil, nil
+}
+
+// GetConditionsByReason returns the conditions with the reason, the reason
+// of a condition refers to the owner of the resource of the condition
+func (r *Kptfile) GetConditionsByReason(reason string) ([]Condition, error) {
+	conditions, err := r.GetConditions()
+	if err != nil {
+		return nil, err
+	}
+	filtered := []Condition{}
+	for _, c := range conditions {
+		if c.Reason == reason {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered, nil
+}
+
+// SetConditions sets the conditions in the status of the Kptfile. An
+// existing condition with the same type is updated in place, otherwise the
+// condition is appended. The other conditions are not re-encoded, such that
+// they keep their formatting and the order of their fields.
+func (r *Kptfile) SetConditions(conditions ...Condition) error {
+	existing, _, err := r.NestedSlice(ConditionsField...)
+	if err != nil {
+		return err
+	}
+	appended := false
+	for _, c := range conditions {
+		so := findCondition(existing, c.Type)
+		if so == nil {
+			so = &fn.SubObject{}
+			existing = append(existing, so)
+			appended = true
+		}
+		if err := setCondition(so, c); err != nil {
+			return err
+		}
+	}
+	if !appended {
+		return nil
+	}
+	return r.UpsertMap(ConditionsField[0]).SetSlice(existing, ConditionsField[1])
+}
+
+// DeleteConditions deletes the conditions with the types from the status of
+// the Kptfile, the remaining conditions are not re-encoded. After a delete
+// the conditions no longer line up by index with the input of the function,
+// while the function runtime of kpt syncs the order of the fields of list
+// entries by index with the input resource matched through the id
+// annotation. The id annotation is therefore removed, which is fine as the
+// remaining conditions already keep their comments and field order.
+func (r *Kptfile) DeleteConditions(conditionTypes ...string) error {
+	existing, found, err := r.NestedSlice(ConditionsField...)
+	if err != nil || !found {
+		return err
+	}
+	types := map[string]struct{}{}
+	for _, t := range conditionTypes {
+		types[t] = struct{}{}
+	}
+	conditions := fn.SliceSubObjects{}
+	for _, so := range existing {
+		if _, ok := types[so.GetString("type")]; ok {
+			continue
+		}
+		conditions = append(conditions, so)
+	}
+	if len(conditions) == len(existing) {
+		return nil
+	}
+	for _, a := range []string{kioutil.IdAnnotation, kioutil.LegacyIdAnnotation} {
+		if _, err := r.RemoveNestedField("metadata", "annotations", a); err != nil {
+			return err
+		}
+	}
+	return r.UpsertMap(ConditionsField[0]).SetSlice(conditions, ConditionsField[1])
+}
+
+// findCondition returns the condition with the type, nil is returned if the
+// condition does not exist
+func findCondition(conditions fn.SliceSubObjects, conditionType string) *fn.SubObject {
+	for _, so := range conditions {
+		if so.GetString("type") == conditionType {
+			return so
+		}
+	}
+	return nil
+}
+
+// setCondition sets the fields of the condition, the fields that exist are
+// updated in place and the optional fields that are empty are removed
+func setCondition(so *fn.SubObject, c Condition) error {
+	for _, x := range []struct {
+		field    string
+		value    string
+		optional bool
+	}{
+		{field: "type", value: c.Type},
+		{field: "status", value: string(c.Status)},
+		{field: "reason", value: c.Reason, optional: true},
+		{field: "message", value: c.Message, optional: true},
+	} {
+		if x.optional && x.value == "" {
+			if _, err := so.RemoveNestedField(x.field); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := so.SetNestedString(x.value, x.field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2023 The Nephio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	KptfileName = "Kptfile"
+)
+
+var (
+	KptfileGVK = schema.GroupVersionKind{Group: "kpt.dev", Version: "v1", Kind: "Kptfile"}
+)
+
+// KptFile is the typed representation of the Kptfile of a package, the
+// upstream fields are not modelled and are kept as is by the setters
+type KptFile struct {
+	metav1.TypeMeta   `json:",inline" yaml:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+
+	Info      *PackageInfo `json:"info,omitempty" yaml:"info,omitempty"`
+	Pipeline  *Pipeline    `json:"pipeline,omitempty" yaml:"pipeline,omitempty"`
+	Inventory *Inventory   `json:"inventory,omitempty" yaml:"inventory,omitempty"`
+	Status    *Status      `json:"status,omitempty" yaml:"status,omitempty"`
+}
+
+// PackageInfo contains the metadata of the package
+type PackageInfo struct {
+	Site           string          `json:"site,omitempty" yaml:"site,omitempty"`
+	Emails         []string        `json:"emails,omitempty" yaml:"emails,omitempty"`
+	License        string          `json:"license,omitempty" yaml:"license,omitempty"`
+	LicenseFile    string          `json:"licenseFile,omitempty" yaml:"licenseFile,omitempty"`
+	Description    string          `json:"description,omitempty" yaml:"description,omitempty"`
+	Keywords       []string        `json:"keywords,omitempty" yaml:"keywords,omitempty"`
+	Man            string          `json:"man,omitempty" yaml:"man,omitempty"`
+	ReadinessGates []ReadinessGate `json:"readinessGates,omitempty" yaml:"readinessGates,omitempty"`
+}
+
+// ReadinessGate is a condition type the readiness of the package depends on
+type ReadinessGate struct {
+	ConditionType string `json:"conditionType" yaml:"conditionType"`
+}
+
+// Pipeline is the list of functions run on the package when it is rendered
+type Pipeline struct {
+	Mutators   []Function `json:"mutators,omitempty" yaml:"mutators,omitempty"`
+	Validators []Function `json:"validators,omitempty" yaml:"validators,omitempty"`
+}
+
+// Function is a function in the pipeline
+type Function struct {
+	Image      string            `json:"image,omitempty" yaml:"image,omitempty"`
+	Exec       string            `json:"exec,omitempty" yaml:"exec,omitempty"`
+	ConfigPath string            `json:"configPath,omitempty" yaml:"configPath,omitempty"`
+	ConfigMap  map[string]string `json:"configMap,omitempty" yaml:"configMap,omitempty"`
+	Name       string            `json:"name,omitempty" yaml:"name,omitempty"`
+	Selectors  []Selector        `json:"selectors,omitempty" yaml:"selectors,omitempty"`
+	Exclusions []Selector        `json:"exclude,omitempty" yaml:"exclude,omitempty"`
+}
+
+// Selector selects the resources a function is run on
+type Selector struct {
+	APIVersion  string            `json:"apiVersion,omitempty" yaml:"apiVersion,omitempty"`
+	Kind        string            `json:"kind,omitempty" yaml:"kind,omitempty"`
+	Name        string            `json:"name,omitempty" yaml:"name,omitempty"`
+	Namespace   string            `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+}
+
+// Inventory identifies the inventory object of the package used by kpt live
+type Inventory struct {
+	Namespace   string            `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	Name        string            `json:"name,omitempty" yaml:"name,omitempty"`
+	InventoryID string            `json:"inventoryID,omitempty" yaml:"inventoryID,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+}
+
+// Status is the status of the package
+type Status struct {
+	Conditions []Condition `json:"conditions,omitempty" yaml:"conditions,omitempty"`
+}
+
+type ConditionStatus string
+
+const (
+	ConditionTrue    ConditionStatus = "True"
+	ConditionFalse   ConditionStatus = "False"
+	ConditionUnknown ConditionStatus = "Unknown"
+)
+
+// Condition is a condition in the status of the package
+type Condition struct {
+	Type    string          `json:"type" yaml:"type"`
+	Status  ConditionStatus `json:"status" yaml:"status"`
+	Reason  string          `json:"reason,omitempty" yaml:"reason,omitempty"`
+	Message string          `json:"message,omitempty" yaml:"message,omitempty"`
+}
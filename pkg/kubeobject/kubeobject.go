@@ -85,6 +85,12 @@ func (o *KubeObjectExt[T1]) SetFromTypedObject(value T1) error {
 	return setNestedFieldKeepFormatting(&o.KubeObject, value)
 }
 
+// SetNestedFieldKeepFormatting sets the field of a KubeObjectExt at the path of `fields` to the
+// value of `value`, while trying to keep as much formatting as possible
+func (o *KubeObjectExt[T1]) SetNestedFieldKeepFormatting(value interface{}, fields ...string) error {
+	return setNestedFieldKeepFormatting(&o.KubeObject, value, fields...)
+}
+
 // setNestedFieldKeepFormatting is similar to KubeObject.SetNestedField(), but keeps the
 // comments and the order of fields in the YAML wherever it is possible.
 //
@@ -0,0 +1,110 @@
+/*
+Copyright 2023 The Nephio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package localconfig
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/GoogleContainerTools/kpt-functions-sdk/go/fn"
+	"github.com/henderiw-nephio/pkg-examples/pkg/pkgcontext"
+)
+
+const (
+	// Annotation marks a resource as local to the package, such resources
+	// are never applied to the cluster
+	Annotation = "config.kubernetes.io/local-config"
+	// TemplateAnnotation marks a ConfigMap as a template, the value is the
+	// name of the ConfigMap rendered from it. Only the rendered ConfigMap is
+	// applied to the cluster.
+	TemplateAnnotation = "nephio.org/config-template"
+)
+
+var (
+	// localGroups contains the API groups of the requirement and allocation
+	// resources that only exist to drive the functions in the pipeline
+	localGroups = map[string]struct{}{
+		"kpt.dev":               {},
+		"req.nephio.org":        {},
+		"infra.nephio.org":      {},
+		"ipam.alloc.nephio.org": {},
+		"vlan.alloc.nephio.org": {},
+	}
+	// appliedGroups contains the API groups of the resources that are
+	// expected to be applied to the cluster
+	appliedGroups = map[string]struct{}{
+		"k8s.cni.cncf.io":     {},
+		"workload.nephio.org": {},
+	}
+)
+
+// Set sets the local-config annotation on the object
+func Set(o *fn.KubeObject, local bool) error {
+	if o == nil {
+		return fmt.Errorf("cannot set annotation on a nil object")
+	}
+	return o.SetAnnotation(Annotation, strconv.FormatBool(local))
+}
+
+// IsLocal returns true if the object is marked with the local-config annotation
+func IsLocal(o *fn.KubeObject) bool {
+	if o == nil {
+		return false
+	}
+	local, err := strconv.ParseBool(o.GetAnnotation(Annotation))
+	if err != nil {
+		return false
+	}
+	return local
+}
+
+// IsLocalKind returns true if the object is of a kind that should always
+// be marked as local-config
+func IsLocalKind(o *fn.KubeObject) bool {
+	if o.GetKind() == "ConfigMap" && (o.GetName() == pkgcontext.ConfigMapName || o.GetAnnotation(TemplateAnnotation) != "") {
+		return true
+	}
+	_, ok := localGroups[o.GroupKind().Group]
+	return ok
+}
+
+// IsAppliedKind returns true if the object is of a kind that should always
+// be applied to the cluster
+func IsAppliedKind(o *fn.KubeObject) bool {
+	_, ok := appliedGroups[o.GroupKind().Group]
+	return ok
+}
+
+// Validate returns a result for every object in the list whose local-config
+// annotation doesn't match its kind: an error for requirement objects that would
+// leak to the cluster and a warning for objects that would never be applied.
+func Validate(objs fn.KubeObjects) fn.Results {
+	results := fn.Results{}
+	for _, o := range objs {
+		switch {
+		case IsLocalKind(o) && !IsLocal(o):
+			results = append(results, fn.ConfigObjectResult(
+				fmt.Sprintf("%s %q would be applied to the cluster, expected annotation %s: \"true\"", o.GetKind(), o.GetName(), Annotation),
+				o, fn.Error))
+		case IsAppliedKind(o) && IsLocal(o):
+			results = append(results, fn.ConfigObjectResult(
+				fmt.Sprintf("%s %q would not be applied to the cluster since it is marked with annotation %s: \"true\"", o.GetKind(), o.GetName(), Annotation),
+				o, fn.Warning))
+		}
+	}
+	return results
+}
@@ -0,0 +1,242 @@
+/*
+Copyright 2023 The Nephio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package macalloc
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net"
+	"sort"
+
+	"github.com/GoogleContainerTools/kpt-functions-sdk/go/fn"
+	"github.com/henderiw-nephio/pkg-examples/pkg/localconfig"
+)
+
+const (
+	// ConfigMapName is the name of the local ConfigMap in the package that
+	// persists the mac allocations across runs of the pipeline
+	ConfigMapName = "mac-allocations"
+
+	// poolSize is the amount of addresses in the pool of a site, the index
+	// 0 is not handed out
+	poolSize = 1 << 24
+)
+
+var (
+	// StatusField is the path in the Interface status that lists the mac
+	// addresses of the replicas in the order of the replica index, next to
+	// the ip allocation status of the replicas. The functions rewriting the
+	// Interface from its typed struct carry it over as it is not part of the
+	// struct.
+	StatusField = []string{"status", "macAddresses"}
+)
+
+// Get returns the mac addresses of the replicas of the object by replica
+// index
+func Get(o *fn.KubeObject) []string {
+	macs, _, err := o.NestedStringSlice(StatusField...)
+	if err != nil {
+		return nil
+	}
+	return macs
+}
+
+// GetReplica returns the mac address of the replica with the given index, an
+// empty string is returned if the replica has no mac address
+func GetReplica(o *fn.KubeObject, index int) string {
+	macs := Get(o)
+	if index < 0 || index >= len(macs) {
+		return ""
+	}
+	return macs[index]
+}
+
+// Set sets the mac addresses of the replicas of the object by replica index
+func Set(o *fn.KubeObject, macs []string) error {
+	if len(macs) == 0 {
+		_, err := o.RemoveNestedField(StatusField...)
+		return err
+	}
+	return o.SetNestedStringSlice(macs, StatusField...)
+}
+
+// Key returns the key of the allocation of the replica with the given index
+// of the interface. The first replica keeps the name of the interface, the
+// other ones get a suffix with an underscore, which is not allowed in a name,
+// such that the key of a replica cannot collide with another interface.
+func Key(name string, index int) string {
+	if index == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s_%d", name, index)
+}
+
+// Backend allocates mac addresses for a key from a pool
+type Backend interface {
+	// Allocate returns the mac address of the key in the pool, an existing
+	// allocation is returned if it belongs to the pool
+	Allocate(pool Pool, key string) (string, error)
+	// Release releases the mac address of the key
+	Release(key string)
+	// Keys returns the keys of all allocations
+	Keys() []string
+}
+
+// Pool is the range of locally administered unicast mac addresses of a site
+type Pool struct {
+	prefix [3]byte
+}
+
+// NewPool returns the pool of the site with a prefix derived from the site
+// code. Different sites can hash to the same prefix, hence sites sharing a
+// layer 2 domain should configure distinct prefixes through ParsePool.
+func NewPool(site string) Pool {
+	h := fnv.New32a()
+	h.Write([]byte(site))
+	sum := h.Sum32()
+	return Pool{
+		prefix: [3]byte{
+			// set the locally administered bit and clear the multicast bit
+			(byte(sum>>24) | 0x02) & 0xfe,
+			byte(sum >> 16),
+			byte(sum >> 8),
+		},
+	}
+}
+
+// ParsePool returns the pool with the given prefix of 3 octets, e.g.
+// 02:1a:2b, which must be a locally administered unicast prefix
+func ParsePool(prefix string) (Pool, error) {
+	hw, err := net.ParseMAC(prefix + ":00:00:00")
+	if err != nil || len(hw) != 6 {
+		return Pool{}, fmt.Errorf("invalid mac prefix %q, expected 3 octets like 02:1a:2b", prefix)
+	}
+	if hw[0]&0x02 == 0 || hw[0]&0x01 != 0 {
+		return Pool{}, fmt.Errorf("invalid mac prefix %q, expected a locally administered unicast prefix", prefix)
+	}
+	return Pool{prefix: [3]byte{hw[0], hw[1], hw[2]}}, nil
+}
+
+// String returns the prefix of the pool
+func (r Pool) String() string {
+	return net.HardwareAddr(r.prefix[:]).String()
+}
+
+// Get returns the mac address with the given index in the pool
+func (r Pool) Get(index uint32) string {
+	return net.HardwareAddr{
+		r.prefix[0], r.prefix[1], r.prefix[2],
+		byte(index >> 16), byte(index >> 8), byte(index),
+	}.String()
+}
+
+// Contains returns true if the mac address belongs to the pool
+func (r Pool) Contains(mac string) bool {
+	hw, err := net.ParseMAC(mac)
+	if err != nil || len(hw) != 6 {
+		return false
+	}
+	return hw[0] == r.prefix[0] && hw[1] == r.prefix[1] && hw[2] == r.prefix[2]
+}
+
+// NewConfigMapBackend returns a Backend persisting the allocations in the
+// local mac-allocations ConfigMap of the package
+func NewConfigMapBackend(objs fn.KubeObjects) (*ConfigMapBackend, error) {
+	r := &ConfigMapBackend{
+		allocations: map[string]string{},
+	}
+	for _, o := range objs.Where(fn.IsGVK("", "v1", "ConfigMap")).Where(fn.IsName(ConfigMapName)) {
+		data, _, err := o.NestedStringMap("data")
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range data {
+			r.allocations[k] = v
+		}
+		r.namespace = o.GetNamespace()
+	}
+	return r, nil
+}
+
+// ConfigMapBackend is a Backend persisting the allocations in a ConfigMap
+type ConfigMapBackend struct {
+	namespace   string
+	allocations map[string]string
+}
+
+// Allocate implements Backend
+func (r *ConfigMapBackend) Allocate(pool Pool, key string) (string, error) {
+	if mac, ok := r.allocations[key]; ok && pool.Contains(mac) {
+		return mac, nil
+	}
+	used := map[string]struct{}{}
+	for _, mac := range r.allocations {
+		used[mac] = struct{}{}
+	}
+	for index := uint32(1); index < poolSize; index++ {
+		mac := pool.Get(index)
+		if _, ok := used[mac]; !ok {
+			r.allocations[key] = mac
+			return mac, nil
+		}
+	}
+	return "", fmt.Errorf("no mac address available in pool %s", pool)
+}
+
+// Release implements Backend
+func (r *ConfigMapBackend) Release(key string) {
+	delete(r.allocations, key)
+}
+
+// Keys implements Backend
+func (r *ConfigMapBackend) Keys() []string {
+	keys := make([]string, 0, len(r.allocations))
+	for k := range r.allocations {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// KubeObject returns the local ConfigMap holding the allocations
+func (r *ConfigMapBackend) KubeObject(namespace string) (*fn.KubeObject, error) {
+	o := fn.NewEmptyKubeObject()
+	if err := o.SetAPIVersion("v1"); err != nil {
+		return nil, err
+	}
+	if err := o.SetKind("ConfigMap"); err != nil {
+		return nil, err
+	}
+	if err := o.SetName(ConfigMapName); err != nil {
+		return nil, err
+	}
+	if r.namespace != "" {
+		namespace = r.namespace
+	}
+	if namespace != "" {
+		if err := o.SetNamespace(namespace); err != nil {
+			return nil, err
+		}
+	}
+	if err := localconfig.Set(o, true); err != nil {
+		return nil, err
+	}
+	if err := o.SetNestedStringMap(r.allocations, "data"); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2023 The Nephio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package multinetworkpolicy
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// PolicyForAnnotation selects the network attachment definitions the
+	// policy applies to
+	PolicyForAnnotation = "k8s.v1.cni.cncf.io/policy-for"
+)
+
+var (
+	GroupVersion = schema.GroupVersion{Group: "k8s.cni.cncf.io", Version: "v1beta1"}
+	Kind         = "MultiNetworkPolicy"
+	// GroupVersionKind is the gvk of the MultiNetworkPolicy
+	GroupVersionKind = GroupVersion.WithKind(Kind)
+)
+
+// MultiNetworkPolicy is a NetworkPolicy applied to the secondary networks
+// of a pod, the networks are selected with the policy-for annotation
+type MultiNetworkPolicy struct {
+	metav1.TypeMeta   `json:",inline" yaml:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	Spec              networkingv1.NetworkPolicySpec `json:"spec,omitempty" yaml:"spec,omitempty"`
+}
+
+// BuildMultiNetworkPolicy returns a MultiNetworkPolicy for the nads that only
+// allows ingress traffic from and egress traffic to the given prefixes
+func BuildMultiNetworkPolicy(meta metav1.ObjectMeta, nads []string, prefixes []string) *MultiNetworkPolicy {
+	if meta.Annotations == nil {
+		meta.Annotations = map[string]string{}
+	}
+	meta.Annotations[PolicyForAnnotation] = strings.Join(nads, ",")
+
+	peers := make([]networkingv1.NetworkPolicyPeer, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		peers = append(peers, networkingv1.NetworkPolicyPeer{
+			IPBlock: &networkingv1.IPBlock{CIDR: prefix},
+		})
+	}
+	return &MultiNetworkPolicy{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: GroupVersion.Identifier(),
+			Kind:       Kind,
+		},
+		ObjectMeta: meta,
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{},
+			PolicyTypes: []networkingv1.PolicyType{
+				networkingv1.PolicyTypeIngress,
+				networkingv1.PolicyTypeEgress,
+			},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{{From: peers}},
+			Egress:  []networkingv1.NetworkPolicyEgressRule{{To: peers}},
+		},
+	}
+}
+
+// GetNetworks returns the sorted and deduplicated networks of the prefixes,
+// e.g. 10.0.0.10/24 -> 10.0.0.0/24. A prefix without a prefix length is
+// handled as a host prefix.
+func GetNetworks(prefixes ...string) ([]string, error) {
+	networks := map[string]struct{}{}
+	for _, prefix := range prefixes {
+		if prefix == "" {
+			continue
+		}
+		if !strings.Contains(prefix, "/") {
+			addr, err := netip.ParseAddr(prefix)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse prefix %q, err: %v", prefix, err)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen()).String()
+		}
+		p, err := netip.ParsePrefix(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse prefix %q, err: %v", prefix, err)
+		}
+		networks[p.Masked().String()] = struct{}{}
+	}
+	result := make([]string, 0, len(networks))
+	for network := range networks {
+		result = append(result, network)
+	}
+	sort.Strings(result)
+	return result, nil
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2023 The Nephio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package multus
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/GoogleContainerTools/kpt-functions-sdk/go/fn"
+	nadv1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+)
+
+var (
+	// PodTemplateAnnotations is the path of the pod template annotations
+	// in a Deployment, StatefulSet or DaemonSet
+	PodTemplateAnnotations = []string{"spec", "template", "metadata", "annotations"}
+)
+
+// HasPodTemplate returns true if the workload carries a pod template
+// the network selection annotation is set on
+func HasPodTemplate(o *fn.KubeObject) bool {
+	return o.GroupKind().Group == "apps"
+}
+
+// GetNetworkSelection returns the network selection elements of the workload.
+// For workloads with a pod template the annotation of the pod template is used,
+// otherwise the annotation of the workload itself.
+func GetNetworkSelection(o *fn.KubeObject) ([]nadv1.NetworkSelectionElement, error) {
+	elems := []nadv1.NetworkSelectionElement{}
+	var s string
+	if HasPodTemplate(o) {
+		annotations, _, err := o.NestedStringMap(PodTemplateAnnotations...)
+		if err != nil {
+			return nil, err
+		}
+		s = annotations[nadv1.NetworkAttachmentAnnot]
+	} else {
+		s = o.GetAnnotation(nadv1.NetworkAttachmentAnnot)
+	}
+	if s == "" {
+		return elems, nil
+	}
+	if err := json.Unmarshal([]byte(s), &elems); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal annotation %s of %s %q, err: %v", nadv1.NetworkAttachmentAnnot, o.GetKind(), o.GetName(), err)
+	}
+	return elems, nil
+}
+
+// SetNetworkSelection sets the network selection elements on the workload.
+// The elements are sorted by interface name to keep the annotation stable
+// across runs.
+func SetNetworkSelection(o *fn.KubeObject, elems []nadv1.NetworkSelectionElement) error {
+	sort.SliceStable(elems, func(i, j int) bool {
+		return elems[i].InterfaceRequest < elems[j].InterfaceRequest
+	})
+	b, err := json.Marshal(elems)
+	if err != nil {
+		return err
+	}
+	if !HasPodTemplate(o) {
+		return o.SetAnnotation(nadv1.NetworkAttachmentAnnot, string(b))
+	}
+	annotations, _, err := o.NestedStringMap(PodTemplateAnnotations...)
+	if err != nil {
+		return err
+	}
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[nadv1.NetworkAttachmentAnnot] = string(b)
+	return o.SetNestedStringMap(annotations, PodTemplateAnnotations...)
+}
+
+// Key identifies the nad selected by a network selection element
+type Key struct {
+	Namespace string
+	Name      string
+}
+
+// GetKey returns the key of the nad selected by the element of the workload,
+// an element without namespace selects the nad in the namespace of the
+// workload
+func GetKey(o *fn.KubeObject, elem nadv1.NetworkSelectionElement) Key {
+	if elem.Namespace == "" {
+		return Key{Namespace: o.GetNamespace(), Name: elem.Name}
+	}
+	return Key{Namespace: elem.Namespace, Name: elem.Name}
+}
+
+// MergeNetworkSelection merges the elements in the network selection of the
+// workload by the nad they select. An element keeps the fields it does not
+// set from the existing element selecting the same nad, such as a default
+// route added by the user. The other existing elements are kept in front,
+// unless isManaged returns true for them, such that the elements of a nad
+// that is no longer generated are removed.
+func MergeNetworkSelection(o *fn.KubeObject, elems []nadv1.NetworkSelectionElement, isManaged func(nadv1.NetworkSelectionElement) bool) error {
+	existing, err := GetNetworkSelection(o)
+	if err != nil {
+		return err
+	}
+	existingElems := map[Key]nadv1.NetworkSelectionElement{}
+	for _, elem := range existing {
+		existingElems[GetKey(o, elem)] = elem
+	}
+	newKeys := map[Key]struct{}{}
+	merged := make([]nadv1.NetworkSelectionElement, 0, len(existing)+len(elems))
+	for _, newElem := range elems {
+		key := GetKey(o, newElem)
+		newKeys[key] = struct{}{}
+		elem, ok := existingElems[key]
+		if !ok {
+			merged = append(merged, newElem)
+			continue
+		}
+		elem.Namespace = newElem.Namespace
+		elem.InterfaceRequest = newElem.InterfaceRequest
+		elem.IPRequest = newElem.IPRequest
+		elem.MacRequest = newElem.MacRequest
+		merged = append(merged, elem)
+	}
+	kept := make([]nadv1.NetworkSelectionElement, 0, len(existing))
+	for _, elem := range existing {
+		if _, ok := newKeys[GetKey(o, elem)]; ok || isManaged(elem) {
+			continue
+		}
+		kept = append(kept, elem)
+	}
+	return SetNetworkSelection(o, append(kept, merged...))
+}
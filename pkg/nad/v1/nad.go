@@ -19,11 +19,16 @@ package v1
 import (
 	"encoding/json"
 	"fmt"
+	"net"
+	"net/netip"
 	"reflect"
+	"sort"
+	"strings"
 
 	"github.com/GoogleContainerTools/kpt-functions-sdk/go/fn"
 	nadv1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
 	"github.com/nephio-project/nephio/krm-functions/lib/kubeobject"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -33,6 +38,21 @@ const (
 	CniVersion                  = "0.3.1"
 	NadMode                     = "bridge"
 	NadType                     = "static"
+
+	// ipam types
+	IpamTypeStatic      = NadType
+	IpamTypeWhereabouts = "whereabouts"
+	IpamTypeHostLocal   = "host-local"
+	// IpamTypeAnnotation allows to override the ipam type of the nad per
+	// interface
+	IpamTypeAnnotation = "nephio.org/ipam-type"
+
+	// BandwidthType is the type of the bandwidth plugin chained after the
+	// main plugin to shape the traffic of the interface
+	BandwidthType = "bandwidth"
+	// bandwidthBurstDivider sets the burst to the amount of bits sent at the
+	// rate during 100ms
+	bandwidthBurstDivider = 10
 )
 
 var (
@@ -48,19 +68,51 @@ type NadConfig struct {
 type PluginCniType struct {
 	Type         string       `json:"type"`
 	Capabilities Capabilities `json:"capabilities"`
-	Master       string       `json:"master"`
-	Mode         string       `json:"mode"`
+	Master       string       `json:"master,omitempty"`
+	Mode         string       `json:"mode,omitempty"`
 	Ipam         Ipam         `json:"ipam"`
+	// IngressRate, IngressBurst, EgressRate and EgressBurst are used by the
+	// bandwidth plugin, rates are expressed in bits per second and bursts in bits
+	IngressRate  int64 `json:"ingressRate,omitempty"`
+	IngressBurst int64 `json:"ingressBurst,omitempty"`
+	EgressRate   int64 `json:"egressRate,omitempty"`
+	EgressBurst  int64 `json:"egressBurst,omitempty"`
+}
+
+// MarshalJSON omits the ipam of plugins that don't allocate addresses, such
+// as the bandwidth plugin
+func (r PluginCniType) MarshalJSON() ([]byte, error) {
+	type plugin PluginCniType
+	if !reflect.ValueOf(r.Ipam).IsZero() {
+		return json.Marshal(plugin(r))
+	}
+	return json.Marshal(struct {
+		plugin
+		Ipam *Ipam `json:"ipam,omitempty"`
+	}{plugin: plugin(r)})
 }
 
 type Capabilities struct {
-	Ips bool `json:"ips"`
-	Mac bool `json:"mac"`
+	Ips       bool `json:"ips"`
+	Mac       bool `json:"mac"`
+	Bandwidth bool `json:"bandwidth,omitempty"`
 }
 
 type Ipam struct {
-	Type      string      `json:"type"`
-	Addresses []Addresses `json:"addresses"`
+	Type string `json:"type"`
+	// Addresses is used by the static ipam type
+	Addresses []Addresses `json:"addresses,omitempty"`
+	// Range, RangeStart, RangeEnd, Exclude and Gateway are used by the
+	// whereabouts ipam type
+	Range      string   `json:"range,omitempty"`
+	RangeStart string   `json:"range_start,omitempty"`
+	RangeEnd   string   `json:"range_end,omitempty"`
+	Exclude    []string `json:"exclude,omitempty"`
+	Gateway    string   `json:"gateway,omitempty"`
+	// Ranges is used by the host-local ipam type
+	Ranges [][]Range `json:"ranges,omitempty"`
+	Routes []Route   `json:"routes,omitempty"`
+	DNS    *DNS      `json:"dns,omitempty"`
 }
 
 type Addresses struct {
@@ -68,6 +120,227 @@ type Addresses struct {
 	Gateway string `json:"gateway"`
 }
 
+type Range struct {
+	Subnet     string `json:"subnet"`
+	RangeStart string `json:"rangeStart,omitempty"`
+	RangeEnd   string `json:"rangeEnd,omitempty"`
+	Gateway    string `json:"gateway,omitempty"`
+}
+
+type Route struct {
+	Dst string `json:"dst"`
+	Gw  string `json:"gw,omitempty"`
+}
+
+type DNS struct {
+	Nameservers []string `json:"nameservers,omitempty"`
+	Domain      string   `json:"domain,omitempty"`
+	Search      []string `json:"search,omitempty"`
+	Options     []string `json:"options,omitempty"`
+}
+
+// IsIpamTypeSupported returns true if the ipam type can be generated
+func IsIpamTypeSupported(s string) bool {
+	switch s {
+	case IpamTypeStatic, IpamTypeWhereabouts, IpamTypeHostLocal:
+		return true
+	}
+	return false
+}
+
+// IsSharedIpamType returns true if the ipam type hands out addresses to the
+// pods attaching the nad, such that the replicas of an interface can share a
+// single nad. A static ipam pins its addresses, hence every replica needs a
+// nad of its own.
+func IsSharedIpamType(s string) bool {
+	return s == IpamTypeWhereabouts || s == IpamTypeHostLocal
+}
+
+// BuildIpam returns the ipam block of the given type for the prefix allocated
+// to the interface. The static type uses the prefix as a fixed address, while
+// whereabouts and host-local hand out addresses from the network the prefix
+// belongs to, excluding the gateway.
+func BuildIpam(ipamType, prefix, gateway string) (Ipam, error) {
+	switch ipamType {
+	case IpamTypeStatic:
+		return Ipam{
+			Type:      IpamTypeStatic,
+			Addresses: []Addresses{{Address: prefix, Gateway: gateway}},
+		}, nil
+	case IpamTypeWhereabouts:
+		network, err := getNetwork(prefix)
+		if err != nil {
+			return Ipam{}, err
+		}
+		ipam := Ipam{
+			Type:    IpamTypeWhereabouts,
+			Range:   network,
+			Gateway: gateway,
+		}
+		if gateway != "" {
+			ipam.Exclude = []string{getHostPrefix(gateway)}
+		}
+		return ipam, nil
+	case IpamTypeHostLocal:
+		network, err := getNetwork(prefix)
+		if err != nil {
+			return Ipam{}, err
+		}
+		return Ipam{
+			Type:   IpamTypeHostLocal,
+			Ranges: [][]Range{{{Subnet: network, Gateway: gateway}}},
+		}, nil
+	}
+	return Ipam{}, fmt.Errorf("unsupported ipam type: %s", ipamType)
+}
+
+// ParseRate returns the rate in bits per second of a throughput value such as
+// 10G, 500M or 1Gbps. The units are decimal as is common for throughput.
+func ParseRate(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	for _, suffix := range []string{"bps", "b/s"} {
+		s = strings.TrimSuffix(s, suffix)
+	}
+	q, err := resource.ParseQuantity(s)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse rate %q, err: %v", s, err)
+	}
+	if q.Sign() < 0 {
+		return 0, fmt.Errorf("invalid rate %q, expected a positive value", s)
+	}
+	return q.Value(), nil
+}
+
+// BuildBandwidthPlugin returns the bandwidth plugin shaping the ingress and
+// egress traffic of the interface to the given rates in bits per second.
+// A rate of 0 leaves the traffic in that direction unshaped.
+func BuildBandwidthPlugin(ingressRate, egressRate int64) PluginCniType {
+	return PluginCniType{
+		Type:         BandwidthType,
+		Capabilities: Capabilities{Bandwidth: true},
+		IngressRate:  ingressRate,
+		IngressBurst: ingressRate / bandwidthBurstDivider,
+		EgressRate:   egressRate,
+		EgressBurst:  egressRate / bandwidthBurstDivider,
+	}
+}
+
+// BuildReplicaIpam returns the ipam block of a nad shared by the replicas of
+// an interface. The range of whereabouts and host-local is restricted to
+// exactly the addresses allocated to the replicas, such that no address is
+// handed out that was not allocated. The static type pins its addresses,
+// which cannot be shared by replicas, hence it needs a nad per replica.
+func BuildReplicaIpam(ipamType string, prefixes []string, gateway string) (Ipam, error) {
+	if len(prefixes) == 0 {
+		return Ipam{}, fmt.Errorf("cannot build ipam without prefixes")
+	}
+	ipam, err := BuildIpam(ipamType, prefixes[0], gateway)
+	if err != nil {
+		return Ipam{}, err
+	}
+	if len(prefixes) == 1 && ipamType == IpamTypeStatic {
+		return ipam, nil
+	}
+	addrs, err := getSortedAddrs(prefixes)
+	if err != nil {
+		return Ipam{}, err
+	}
+	switch ipamType {
+	case IpamTypeWhereabouts:
+		// the range spans the allocated addresses and the addresses in
+		// between that were not allocated are excluded
+		ipam.RangeStart = addrs[0].String()
+		ipam.RangeEnd = addrs[len(addrs)-1].String()
+		for i := 1; i < len(addrs); i++ {
+			for _, p := range getRangePrefixes(addrs[i-1].Next(), addrs[i].Prev()) {
+				ipam.Exclude = append(ipam.Exclude, p.String())
+			}
+		}
+	case IpamTypeHostLocal:
+		// a range per allocated address in the range set of the subnet
+		subnet := ipam.Ranges[0][0].Subnet
+		ipam.Ranges = [][]Range{{}}
+		for _, addr := range addrs {
+			ipam.Ranges[0] = append(ipam.Ranges[0], Range{
+				Subnet:     subnet,
+				RangeStart: addr.String(),
+				RangeEnd:   addr.String(),
+				Gateway:    gateway,
+			})
+		}
+	default:
+		return Ipam{}, fmt.Errorf("ipam type %s cannot be shared by %d replicas, every replica needs a nad of its own", ipamType, len(prefixes))
+	}
+	return ipam, nil
+}
+
+// getSortedAddrs returns the sorted addresses of the prefixes
+func getSortedAddrs(prefixes []string) ([]netip.Addr, error) {
+	addrs := make([]netip.Addr, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		p, err := netip.ParsePrefix(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse prefix %q, err: %v", prefix, err)
+		}
+		addrs = append(addrs, p.Addr())
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return addrs[i].Less(addrs[j])
+	})
+	return addrs, nil
+}
+
+// getRangePrefixes returns the smallest set of prefixes covering the addresses
+// from start to end, e.g. 10.0.0.3-10.0.0.8 -> 10.0.0.3/32, 10.0.0.4/30, 10.0.0.8/32
+func getRangePrefixes(start, end netip.Addr) []netip.Prefix {
+	prefixes := []netip.Prefix{}
+	for start.IsValid() && end.IsValid() && !end.Less(start) {
+		bits := start.BitLen()
+		for bits > 0 {
+			p := netip.PrefixFrom(start, bits-1).Masked()
+			if p.Addr() != start || end.Less(getLastAddr(p)) {
+				break
+			}
+			bits--
+		}
+		p := netip.PrefixFrom(start, bits)
+		prefixes = append(prefixes, p)
+		start = getLastAddr(p).Next()
+	}
+	return prefixes
+}
+
+// getLastAddr returns the last address of a prefix
+func getLastAddr(p netip.Prefix) netip.Addr {
+	b := p.Masked().Addr().AsSlice()
+	for i := p.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 1 << (7 - uint(i%8))
+	}
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
+
+// getNetwork returns the network a prefix belongs to, e.g. 10.0.0.10/24 -> 10.0.0.0/24
+func getNetwork(prefix string) (string, error) {
+	_, ipNet, err := net.ParseCIDR(prefix)
+	if err != nil {
+		return "", fmt.Errorf("cannot derive the ipam range from prefix %q, err: %v", prefix, err)
+	}
+	return ipNet.String(), nil
+}
+
+// getHostPrefix returns the host prefix of an ip address, e.g. 10.0.0.1 -> 10.0.0.1/32
+func getHostPrefix(ip string) string {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return ip
+	}
+	if addr.To4() != nil {
+		return ip + "/32"
+	}
+	return ip + "/128"
+}
+
 // NewFromKubeObject creates a new parser interface
 // It expects a *fn.KubeObject as input representing the serialized yaml file
 func NewFromKubeObject(o *fn.KubeObject) (*Nad, error) {
@@ -205,6 +478,28 @@ func (r *Nad) GetIpamAddress() []Addresses {
 	return nadConfigStruct.Plugins[0].Ipam.Addresses
 }
 
+func (r *Nad) GetIpamRoutes() []Route {
+	nadConfigStruct := NadConfig{}
+	if err := json.Unmarshal([]byte(r.GetStringValue(ConfigType...)), &nadConfigStruct); err != nil {
+		return []Route{}
+	}
+	if len(nadConfigStruct.Plugins) == 0 {
+		return []Route{}
+	}
+	return nadConfigStruct.Plugins[0].Ipam.Routes
+}
+
+func (r *Nad) GetIpamDNS() *DNS {
+	nadConfigStruct := NadConfig{}
+	if err := json.Unmarshal([]byte(r.GetStringValue(ConfigType...)), &nadConfigStruct); err != nil {
+		return nil
+	}
+	if len(nadConfigStruct.Plugins) == 0 {
+		return nil
+	}
+	return nadConfigStruct.Plugins[0].Ipam.DNS
+}
+
 // SetConfigSpec sets the spec attributes in the kubeobject according the go struct
 func (r *Nad) SetConfigSpec(spec *nadv1.NetworkAttachmentDefinitionSpec) error {
 	b, err := json.Marshal(spec.Config)
@@ -287,6 +582,54 @@ func (r *Nad) SetIpamAddress(ipam []Addresses) error {
 	}
 }
 
+func (r *Nad) SetIpamRoutes(routes []Route) error {
+	nadConfigStruct := NadConfig{}
+	if err := json.Unmarshal([]byte(r.GetStringValue(ConfigType...)), &nadConfigStruct); err != nil {
+		return err
+	}
+	if len(nadConfigStruct.Plugins) == 0 {
+		return fmt.Errorf("cannot set ipam routes, no plugins in nad config")
+	}
+	nadConfigStruct.Plugins[0].Ipam.Routes = routes
+	b, err := json.Marshal(nadConfigStruct)
+	if err != nil {
+		return err
+	}
+	return r.SetNestedString(string(b), ConfigType...)
+}
+
+func (r *Nad) SetIpamDNS(dns *DNS) error {
+	nadConfigStruct := NadConfig{}
+	if err := json.Unmarshal([]byte(r.GetStringValue(ConfigType...)), &nadConfigStruct); err != nil {
+		return err
+	}
+	if len(nadConfigStruct.Plugins) == 0 {
+		return fmt.Errorf("cannot set ipam dns, no plugins in nad config")
+	}
+	nadConfigStruct.Plugins[0].Ipam.DNS = dns
+	b, err := json.Marshal(nadConfigStruct)
+	if err != nil {
+		return err
+	}
+	return r.SetNestedString(string(b), ConfigType...)
+}
+
+func (r *Nad) SetMacCapability(mac bool) error {
+	nadConfigStruct := NadConfig{}
+	if err := json.Unmarshal([]byte(r.GetStringValue(ConfigType...)), &nadConfigStruct); err != nil {
+		return err
+	}
+	if len(nadConfigStruct.Plugins) == 0 {
+		return fmt.Errorf("cannot set mac capability, no plugins in nad config")
+	}
+	nadConfigStruct.Plugins[0].Capabilities.Mac = mac
+	b, err := json.Marshal(nadConfigStruct)
+	if err != nil {
+		return err
+	}
+	return r.SetNestedString(string(b), ConfigType...)
+}
+
 func BuildNetworkAttachmentDefinition(meta metav1.ObjectMeta, spec nadv1.NetworkAttachmentDefinitionSpec) *nadv1.NetworkAttachmentDefinition {
 	return &nadv1.NetworkAttachmentDefinition{
 		TypeMeta: metav1.TypeMeta{
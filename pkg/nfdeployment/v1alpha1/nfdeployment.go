@@ -0,0 +1,227 @@
+/*
+Copyright 2023 The Nephio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+	"net/netip"
+
+	"github.com/GoogleContainerTools/kpt-functions-sdk/go/fn"
+	"github.com/henderiw-nephio/pkg-examples/pkg/kubeobject"
+	nfv1alpha1 "github.com/nephio-project/api/nf_deployments/v1alpha1"
+	"sigs.k8s.io/yaml"
+)
+
+// Object is implemented by the NF deployment kinds of the nephio api
+type Object interface {
+	*nfv1alpha1.UPFDeployment | *nfv1alpha1.SMFDeployment | *nfv1alpha1.AMFDeployment
+}
+
+// NewFromKubeObject creates a new parser interface
+// It expects a *fn.KubeObject as input representing the serialized yaml file
+func NewFromKubeObject[T Object](o *fn.KubeObject) (*NFDeployment[T], error) {
+	r, err := kubeobject.NewFromKubeObject[T](o)
+	if err != nil {
+		return nil, err
+	}
+	return &NFDeployment[T]{*r}, nil
+}
+
+// NewFromYAML creates a new parser interface
+// It expects a raw byte slice as input representing the serialized yaml file
+func NewFromYAML[T Object](b []byte) (*NFDeployment[T], error) {
+	r, err := kubeobject.NewFromYaml[T](b)
+	if err != nil {
+		return nil, err
+	}
+	return &NFDeployment[T]{*r}, nil
+}
+
+// NewFromGoStruct creates a new parser interface
+// It expects a go struct representing the NF deployment krm resource
+func NewFromGoStruct[T Object](x T) (*NFDeployment[T], error) {
+	r, err := kubeobject.NewFromGoStruct[T](x)
+	if err != nil {
+		return nil, err
+	}
+	return &NFDeployment[T]{*r}, nil
+}
+
+type NFDeployment[T Object] struct {
+	kubeobject.KubeObjectExt[T]
+}
+
+// GetSpec returns the NF deployment spec of the KubeObject
+func (r *NFDeployment[T]) GetSpec() (*nfv1alpha1.NFDeploymentSpec, error) {
+	x, err := r.GetGoStruct()
+	if err != nil {
+		return nil, err
+	}
+	return getNFDeploymentSpec(x), nil
+}
+
+// getNFDeploymentSpec returns the spec shared by the NF deployment kinds
+func getNFDeploymentSpec(x any) *nfv1alpha1.NFDeploymentSpec {
+	switch x := x.(type) {
+	case *nfv1alpha1.UPFDeployment:
+		return &x.Spec.NFDeploymentSpec
+	case *nfv1alpha1.SMFDeployment:
+		return &x.Spec.NFDeploymentSpec
+	case *nfv1alpha1.AMFDeployment:
+		return &x.Spec.NFDeploymentSpec
+	}
+	return nil
+}
+
+// IsNFDeployment returns true if the object is one of the NF deployment kinds
+func IsNFDeployment(o *fn.KubeObject) bool {
+	switch o.GroupVersionKind() {
+	case nfv1alpha1.UPFDeploymenteGroupVersionKind,
+		nfv1alpha1.SMFDeploymenteGroupVersionKind,
+		nfv1alpha1.AMFDeploymenteGroupVersionKind:
+		return true
+	}
+	return false
+}
+
+// Validate returns a result for every issue in the NF deployment object:
+// fields that are not part of the API, malformed addresses and prefixes,
+// and network instances referring to interfaces that don't exist
+func Validate(o *fn.KubeObject) fn.Results {
+	var x any
+	switch o.GroupVersionKind() {
+	case nfv1alpha1.UPFDeploymenteGroupVersionKind:
+		x = &nfv1alpha1.UPFDeployment{}
+	case nfv1alpha1.SMFDeploymenteGroupVersionKind:
+		x = &nfv1alpha1.SMFDeployment{}
+	case nfv1alpha1.AMFDeploymenteGroupVersionKind:
+		x = &nfv1alpha1.AMFDeployment{}
+	default:
+		return fn.Results{fn.ConfigObjectResult(fmt.Sprintf("unsupported kind %s", o.GetKind()), o, fn.Error)}
+	}
+	if err := decodeStrict(o, x); err != nil {
+		return fn.Results{fn.ErrorConfigObjectResult(err, o)}
+	}
+	spec := getNFDeploymentSpec(x)
+
+	results := fn.Results{}
+	addResult := func(path, msg string, value any) {
+		r := fn.ConfigObjectResult(msg, o, fn.Error)
+		r.Field = &fn.Field{Path: path, CurrentValue: value}
+		results = append(results, r)
+	}
+	validateIP := func(path string, ipv4 *nfv1alpha1.IPv4, ipv6 *nfv1alpha1.IPv6) {
+		if ipv4 != nil {
+			validateIPConfig(path+".ipv4", "ipv4", ipv4.Address, ipv4.Gateway, addResult)
+		}
+		if ipv6 != nil {
+			validateIPConfig(path+".ipv6", "ipv6", ipv6.Address, ipv6.Gateway, addResult)
+		}
+	}
+
+	interfaces := map[string]struct{}{}
+	for i, itfce := range spec.Interfaces {
+		path := fmt.Sprintf("spec.interfaces[%d]", i)
+		if _, ok := interfaces[itfce.Name]; ok {
+			addResult(path+".name", fmt.Sprintf("duplicate interface %q", itfce.Name), itfce.Name)
+		}
+		interfaces[itfce.Name] = struct{}{}
+		validateIP(path, itfce.IPv4, itfce.IPv6)
+	}
+
+	for i, ni := range spec.NetworkInstances {
+		path := fmt.Sprintf("spec.networkInstances[%d]", i)
+		for j, itfceName := range ni.Interfaces {
+			if _, ok := interfaces[itfceName]; !ok {
+				addResult(fmt.Sprintf("%s.interfaces[%d]", path, j), fmt.Sprintf("network instance %q refers to interface %q which doesn't exist", ni.Name, itfceName), itfceName)
+			}
+		}
+		for j, peer := range ni.Peers {
+			validateIP(fmt.Sprintf("%s.peers[%d]", path, j), peer.IPv4, peer.IPv6)
+		}
+		for j, dnn := range ni.DataNetworks {
+			for k, pool := range dnn.Pool {
+				if err := validatePrefix(pool.Prefix, ""); err != nil {
+					addResult(fmt.Sprintf("%s.dataNetworks[%d].pool[%d].prefix", path, j, k), err.Error(), pool.Prefix)
+				}
+			}
+		}
+		if ni.BGP != nil {
+			if err := validateAddress(ni.BGP.RouterID, ""); err != nil {
+				addResult(path+".bgp.routerID", err.Error(), ni.BGP.RouterID)
+			}
+			for j, nbr := range ni.BGP.BGPNeigbors {
+				if err := validateAddress(nbr.Address, ""); err != nil {
+					addResult(fmt.Sprintf("%s.bgp.bgpNeighbors[%d].address", path, j), err.Error(), nbr.Address)
+				}
+			}
+		}
+	}
+	return results
+}
+
+// validateIPConfig validates the address in prefix notation and the gateway
+// of an ip configuration of the address family
+func validateIPConfig(path, af, address string, gateway *string, addResult func(path, msg string, value any)) {
+	if err := validatePrefix(address, af); err != nil {
+		addResult(path+".address", err.Error(), address)
+	}
+	if gateway != nil {
+		if err := validateAddress(*gateway, af); err != nil {
+			addResult(path+".gateway", err.Error(), *gateway)
+		}
+	}
+}
+
+// decodeStrict decodes the object into the typed object, failing on fields
+// that are not part of the API
+func decodeStrict(o *fn.KubeObject, x any) error {
+	if err := yaml.UnmarshalStrict([]byte(o.String()), x); err != nil {
+		return fmt.Errorf("invalid %s %q: %v", o.GetKind(), o.GetName(), err)
+	}
+	return nil
+}
+
+// validatePrefix validates an address in prefix notation, e.g. 10.0.0.1/24,
+// of the address family if set
+func validatePrefix(s, af string) error {
+	p, err := netip.ParsePrefix(s)
+	if err != nil {
+		return fmt.Errorf("malformed prefix %q", s)
+	}
+	return validateAddressFamily(p.Addr(), af)
+}
+
+// validateAddress validates an address, e.g. 10.0.0.1, of the address family
+// if set
+func validateAddress(s, af string) error {
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return fmt.Errorf("malformed address %q", s)
+	}
+	return validateAddressFamily(addr, af)
+}
+
+func validateAddressFamily(addr netip.Addr, af string) error {
+	switch {
+	case af == "ipv4" && !addr.Is4():
+		return fmt.Errorf("expected an ipv4 address, got %q", addr)
+	case af == "ipv6" && !addr.Is6():
+		return fmt.Errorf("expected an ipv6 address, got %q", addr)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2023 The Nephio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package orphan
+
+import (
+	"fmt"
+
+	"github.com/GoogleContainerTools/kpt-functions-sdk/go/fn"
+	"github.com/henderiw-nephio/pkg-examples/pkg/fnmeta"
+	kptfilev1 "github.com/henderiw-nephio/pkg-examples/pkg/kptfile/v1"
+	"github.com/henderiw-nephio/pkg-examples/pkg/ownergraph"
+	"github.com/nephio-project/nephio/krm-functions/lib/condkptsdk"
+)
+
+const (
+	deleteMessage = "delete resource"
+)
+
+// Orphan is a child resource or condition whose owner no longer exists in the
+// package, or the condition of a for-object that no longer exists
+type Orphan struct {
+	Node *ownergraph.Node
+	// Owner is the missing owner, nil for the condition of a deleted
+	// for-object
+	Owner *ownergraph.Node
+	// Downstream are the functions processing the kind of the resource, the
+	// resource is deleted by them
+	Downstream []string
+}
+
+// String returns a human readable description of the orphan
+func (r Orphan) String() string {
+	what := "resource"
+	if r.Node.Object == nil {
+		what = "condition"
+	}
+	if r.Owner == nil {
+		return fmt.Sprintf("%s %s %s: for-object no longer exists", what, r.Node.Ref.Kind, r.Node.Ref.Name)
+	}
+	return fmt.Sprintf("%s %s %s: owner %s %s no longer exists", what, r.Node.Ref.Kind, r.Node.Ref.Name, r.Owner.Ref.Kind, r.Owner.Ref.Name)
+}
+
+// Find returns the orphans in the owner graph. Resources that are already
+// marked for deletion are not orphans as the downstream function deletes
+// them.
+func Find(g *ownergraph.Graph, fns []fnmeta.Description) []Orphan {
+	orphans := []Orphan{}
+	for _, node := range g.SortedNodes() {
+		if node.Object != nil && node.Object.GetAnnotation(condkptsdk.SpecializerDelete) == "true" {
+			continue
+		}
+		var orphan *Orphan
+		owners := g.Owners(node.ID())
+		switch {
+		case len(owners) > 0:
+			for _, owner := range owners {
+				if owner.Object != nil {
+					orphan = nil
+					break
+				}
+				orphan = &Orphan{Node: node, Owner: owner}
+			}
+		case node.Object == nil && node.Condition != nil:
+			orphan = &Orphan{Node: node}
+		}
+		if orphan == nil {
+			continue
+		}
+		for _, f := range fnmeta.GetFor(fns, node.Ref.APIVersion(), node.Ref.Kind) {
+			orphan.Downstream = append(orphan.Downstream, f.Name)
+		}
+		orphans = append(orphans, *orphan)
+	}
+	return orphans
+}
+
+// Collect removes the orphans from the package. Following the design rule
+// that deletes are performed downstream, orphan resources processed by a
+// downstream function get the delete annotation and a false condition,
+// other orphan resources are removed together with their condition.
+// Orphan conditions without a resource are removed.
+func Collect(pkg *ownergraph.Package, orphans []Orphan) error {
+	deleted := map[*fn.KubeObject]struct{}{}
+	conditionTypes := []string{}
+	for _, orphan := range orphans {
+		o := orphan.Node.Object
+		if o != nil && len(orphan.Downstream) > 0 {
+			if err := o.SetAnnotation(condkptsdk.SpecializerDelete, "true"); err != nil {
+				return err
+			}
+			c := kptfilev1.Condition{
+				Type:    orphan.Node.ID(),
+				Status:  kptfilev1.ConditionFalse,
+				Message: deleteMessage,
+			}
+			if orphan.Node.Condition != nil {
+				c.Reason = orphan.Node.Condition.Reason
+			}
+			if err := pkg.Kptfile.SetConditions(c); err != nil {
+				return err
+			}
+			continue
+		}
+		if o != nil {
+			deleted[o] = struct{}{}
+		}
+		if orphan.Node.Condition != nil {
+			conditionTypes = append(conditionTypes, orphan.Node.Condition.Type)
+		}
+	}
+	if len(conditionTypes) > 0 {
+		if err := pkg.Kptfile.DeleteConditions(conditionTypes...); err != nil {
+			return err
+		}
+	}
+	objs := fn.KubeObjects{}
+	for _, o := range pkg.Objects {
+		if _, ok := deleted[o]; !ok {
+			objs = append(objs, o)
+		}
+	}
+	pkg.Objects = objs
+	return nil
+}
@@ -0,0 +1,312 @@
+/*
+Copyright 2023 The Nephio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ownergraph
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt-functions-sdk/go/fn"
+	kptfilev1 "github.com/henderiw-nephio/pkg-examples/pkg/kptfile/v1"
+	"github.com/henderiw-nephio/pkg-examples/pkg/readiness"
+	"github.com/nephio-project/nephio/krm-functions/lib/condkptsdk"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+)
+
+// Package is a package read from disk
+type Package struct {
+	Kptfile *kptfilev1.Kptfile
+	Objects fn.KubeObjects
+}
+
+// ReadPackage reads the Kptfile and the resources of the package in the
+// directory
+func ReadPackage(dir string) (*Package, error) {
+	b, err := os.ReadFile(filepath.Join(dir, kptfilev1.KptfileName))
+	if err != nil {
+		return nil, err
+	}
+	kptfile, err := kptfilev1.NewFromYAML(b)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := (&kio.LocalPackageReader{PackagePath: dir, OmitReaderAnnotations: true}).Read()
+	if err != nil {
+		return nil, err
+	}
+	objs := fn.KubeObjects{}
+	for _, node := range nodes {
+		o, err := fn.ParseKubeObject([]byte(node.MustString()))
+		if err != nil {
+			return nil, err
+		}
+		objs = append(objs, o)
+	}
+	return &Package{Kptfile: kptfile, Objects: objs}, nil
+}
+
+// NewPackage returns the package of the resources in a resource list, the
+// Kptfile is taken out of the resources
+func NewPackage(objs fn.KubeObjects) (*Package, error) {
+	pkg := &Package{Objects: fn.KubeObjects{}}
+	for _, o := range objs {
+		if o.GetKind() != kptfilev1.KptfileName {
+			pkg.Objects = append(pkg.Objects, o)
+			continue
+		}
+		kptfile, err := kptfilev1.NewFromKubeObject(o)
+		if err != nil {
+			return nil, err
+		}
+		pkg.Kptfile = kptfile
+	}
+	if pkg.Kptfile == nil {
+		return nil, fmt.Errorf("%s not found in the resources", kptfilev1.KptfileName)
+	}
+	return pkg, nil
+}
+
+// GetOwner returns the owner in the owner annotation of the object, nil is
+// returned if the object has no owner
+func GetOwner(o *fn.KubeObject) (*readiness.Ref, error) {
+	a := o.GetAnnotation(condkptsdk.SpecializerOwner)
+	if a == "" {
+		return nil, nil
+	}
+	ref, err := readiness.ParseConditionType(a)
+	if err != nil {
+		return nil, fmt.Errorf("invalid owner annotation of %s %q: %v", o.GetKind(), o.GetName(), err)
+	}
+	return &ref, nil
+}
+
+// Node is a resource in the graph, a resource is known from the package, from
+// the Kptfile conditions or from both
+type Node struct {
+	Ref       readiness.Ref
+	Object    *fn.KubeObject
+	Condition *kptfilev1.Condition
+}
+
+// ID returns the identifier of the node, which is the condition type of the
+// resource
+func (r *Node) ID() string {
+	return r.Ref.String()
+}
+
+// Status returns the status of the condition of the node, the status is empty
+// if the resource has no condition
+func (r *Node) Status() kptfilev1.ConditionStatus {
+	if r.Condition == nil {
+		return ""
+	}
+	return r.Condition.Status
+}
+
+// Edge points from an owner to a resource it owns
+type Edge struct {
+	From string
+	To   string
+}
+
+// Graph is the owner graph of a package
+type Graph struct {
+	Nodes map[string]*Node
+	Edges []Edge
+}
+
+// Build returns the owner graph of the package. The edges are derived from
+// the owner annotations of the resources and from the reasons of the Kptfile
+// conditions, such that children that do not exist yet in the package are
+// part of the graph.
+func Build(pkg *Package) (*Graph, error) {
+	g := &Graph{Nodes: map[string]*Node{}}
+	edges := map[Edge]struct{}{}
+
+	for _, o := range pkg.Objects {
+		if o.GetKind() == kptfilev1.KptfileName {
+			continue
+		}
+		node := g.getOrAddNode(readiness.NewRef(o))
+		node.Object = o
+		owner, err := GetOwner(o)
+		if err != nil {
+			return nil, err
+		}
+		if owner != nil {
+			g.getOrAddNode(*owner)
+			edges[Edge{From: owner.String(), To: node.ID()}] = struct{}{}
+		}
+	}
+
+	conditions, err := pkg.Kptfile.GetConditions()
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range conditions {
+		if c.Type == readiness.ReadyConditionType {
+			continue
+		}
+		ref, err := readiness.ParseConditionType(c.Type)
+		if err != nil {
+			return nil, err
+		}
+		c := c
+		node := g.getOrAddNode(ref)
+		node.Condition = &c
+		if c.Reason == "" {
+			continue
+		}
+		// the reason of a condition is not always an owner reference
+		owner, err := readiness.ParseConditionType(c.Reason)
+		if err != nil {
+			continue
+		}
+		g.getOrAddNode(owner)
+		edges[Edge{From: owner.String(), To: node.ID()}] = struct{}{}
+	}
+
+	for e := range edges {
+		g.Edges = append(g.Edges, e)
+	}
+	sort.Slice(g.Edges, func(i, j int) bool {
+		if g.Edges[i].From != g.Edges[j].From {
+			return g.Edges[i].From < g.Edges[j].From
+		}
+		return g.Edges[i].To < g.Edges[j].To
+	})
+	return g, nil
+}
+
+func (r *Graph) getOrAddNode(ref readiness.Ref) *Node {
+	node, ok := r.Nodes[ref.String()]
+	if !ok {
+		node = &Node{Ref: ref}
+		r.Nodes[ref.String()] = node
+	}
+	return node
+}
+
+// SortedNodes returns the nodes sorted by their identifier
+func (r *Graph) SortedNodes() []*Node {
+	nodes := make([]*Node, 0, len(r.Nodes))
+	for _, node := range r.Nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].ID() < nodes[j].ID()
+	})
+	return nodes
+}
+
+// Children returns the nodes owned by the node with the identifier
+func (r *Graph) Children(id string) []*Node {
+	children := []*Node{}
+	for _, e := range r.Edges {
+		if e.From == id {
+			children = append(children, r.Nodes[e.To])
+		}
+	}
+	return children
+}
+
+// Owners returns the nodes owning the node with the identifier
+func (r *Graph) Owners(id string) []*Node {
+	owners := []*Node{}
+	for _, e := range r.Edges {
+		if e.To == id {
+			owners = append(owners, r.Nodes[e.From])
+		}
+	}
+	return owners
+}
+
+func label(node *Node) string {
+	l := fmt.Sprintf("%s %s", node.Ref.Kind, node.Ref.Name)
+	if node.Condition != nil && node.Condition.Message != "" {
+		l = fmt.Sprintf("%s\n%s", l, node.Condition.Message)
+	}
+	if node.Object == nil {
+		l = fmt.Sprintf("%s\n(not in package)", l)
+	}
+	return l
+}
+
+func dotColor(status kptfilev1.ConditionStatus) string {
+	switch status {
+	case kptfilev1.ConditionTrue:
+		return "palegreen"
+	case kptfilev1.ConditionFalse:
+		return "lightcoral"
+	case kptfilev1.ConditionUnknown:
+		return "khaki"
+	}
+	return "lightgrey"
+}
+
+// DOT returns the graph in the graphviz DOT format, the nodes are colored by
+// the status of their condition
+func (r *Graph) DOT() string {
+	var sb strings.Builder
+	sb.WriteString("digraph owners {\n")
+	sb.WriteString("  rankdir=LR;\n")
+	sb.WriteString("  node [shape=box, style=filled];\n")
+	for _, node := range r.SortedNodes() {
+		fmt.Fprintf(&sb, "  %q [label=%q, fillcolor=%s];\n", node.ID(), label(node), dotColor(node.Status()))
+	}
+	for _, e := range r.Edges {
+		fmt.Fprintf(&sb, "  %q -> %q;\n", e.From, e.To)
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+func mermaidClass(status kptfilev1.ConditionStatus) string {
+	switch status {
+	case kptfilev1.ConditionTrue:
+		return "ready"
+	case kptfilev1.ConditionFalse:
+		return "notready"
+	case kptfilev1.ConditionUnknown:
+		return "unknown"
+	}
+	return "nocondition"
+}
+
+// Mermaid returns the graph as a mermaid flowchart, the nodes are colored by
+// the status of their condition
+func (r *Graph) Mermaid() string {
+	var sb strings.Builder
+	sb.WriteString("flowchart LR\n")
+	ids := map[string]string{}
+	for i, node := range r.SortedNodes() {
+		ids[node.ID()] = fmt.Sprintf("n%d", i)
+		l := strings.ReplaceAll(label(node), "\n", "<br/>")
+		fmt.Fprintf(&sb, "  %s[\"%s\"]:::%s\n", ids[node.ID()], strings.ReplaceAll(l, "\"", "#quot;"), mermaidClass(node.Status()))
+	}
+	for _, e := range r.Edges {
+		fmt.Fprintf(&sb, "  %s --> %s\n", ids[e.From], ids[e.To])
+	}
+	sb.WriteString("  classDef ready fill:#98fb98\n")
+	sb.WriteString("  classDef notready fill:#f08080\n")
+	sb.WriteString("  classDef unknown fill:#f0e68c\n")
+	sb.WriteString("  classDef nocondition fill:#d3d3d3\n")
+	return sb.String()
+}
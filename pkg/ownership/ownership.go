@@ -0,0 +1,207 @@
+/*
+Copyright 2023 The Nephio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ownership
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt-functions-sdk/go/fn"
+	"github.com/henderiw-nephio/pkg-examples/pkg/children"
+	"github.com/henderiw-nephio/pkg-examples/pkg/fnmeta"
+	"github.com/henderiw-nephio/pkg-examples/pkg/ownergraph"
+	"github.com/henderiw-nephio/pkg-examples/pkg/pkgcontext"
+	"github.com/henderiw-nephio/pkg-examples/pkg/readiness"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	capacityGVK = schema.GroupVersionKind{Group: "req.nephio.org", Version: "v1alpha1", Kind: "Capacity"}
+)
+
+// Violation is an ownership problem of a resource in the package
+type Violation struct {
+	Object   *fn.KubeObject
+	Message  string
+	Severity fn.Severity
+}
+
+// ChildrenFn returns the children a function generates for the for-object
+type ChildrenFn func(f fnmeta.Description, forObj *fn.KubeObject, objs fn.KubeObjects) ([]readiness.Ref, error)
+
+// Children are the functions generating children by function name, they
+// share the naming of the children with the functions
+var Children = map[string]ChildrenFn{
+	"interface-fn": interfaceChildren,
+	"dnn-fn":       dnnChildren,
+}
+
+// CheckOwners returns the children whose owner annotation does not match the
+// for-object of the function that is about to modify them. If no function
+// is provided all functions owning the kind of the child are considered.
+func CheckOwners(objs fn.KubeObjects, fns []fnmeta.Description, function string) ([]Violation, error) {
+	candidates := fns
+	if function != "" {
+		candidates = []fnmeta.Description{}
+		for _, f := range fns {
+			if f.Name == function {
+				candidates = append(candidates, f)
+			}
+		}
+		if len(candidates) == 0 {
+			return nil, fmt.Errorf("unknown function: %s", function)
+		}
+	}
+
+	violations := []Violation{}
+	for _, o := range objs {
+		ref := readiness.NewRef(o)
+		owners := fnmeta.GetOwners(candidates, ref.APIVersion(), ref.Kind)
+		if len(owners) == 0 {
+			continue
+		}
+		names := make([]string, 0, len(owners))
+		for _, f := range owners {
+			names = append(names, f.Name)
+		}
+		owner, err := ownergraph.GetOwner(o)
+		if err != nil {
+			return nil, err
+		}
+		if owner == nil {
+			violations = append(violations, Violation{
+				Object:   o,
+				Message:  fmt.Sprintf("%s %s has no owner annotation and would be taken over by %s", ref.Kind, ref.Name, strings.Join(names, ", ")),
+				Severity: fn.Warning,
+			})
+			continue
+		}
+		matches := false
+		for _, f := range owners {
+			if f.For.Matches(owner.APIVersion(), owner.Kind) {
+				matches = true
+			}
+		}
+		if !matches {
+			violations = append(violations, Violation{
+				Object:   o,
+				Message:  fmt.Sprintf("%s %s is owned by %s %s, which is not a for-object of %s", ref.Kind, ref.Name, owner.Kind, owner.Name, strings.Join(names, ", ")),
+				Severity: fn.Error,
+			})
+		}
+	}
+	return violations, nil
+}
+
+// CheckCollisions returns the children that more than one for-object would
+// generate with the same GVK and name
+func CheckCollisions(objs fn.KubeObjects, fns []fnmeta.Description) ([]Violation, error) {
+	generators := map[string][]*fn.KubeObject{}
+	for _, f := range fns {
+		childrenFn, ok := Children[f.Name]
+		if !ok {
+			continue
+		}
+		for _, forObj := range objs {
+			ref := readiness.NewRef(forObj)
+			if !f.For.Matches(ref.APIVersion(), ref.Kind) {
+				continue
+			}
+			children, err := childrenFn(f, forObj, objs)
+			if err != nil {
+				return nil, err
+			}
+			for _, child := range children {
+				generators[child.String()] = append(generators[child.String()], forObj)
+			}
+		}
+	}
+
+	children := make([]string, 0, len(generators))
+	for child := range generators {
+		children = append(children, child)
+	}
+	sort.Strings(children)
+	violations := []Violation{}
+	for _, child := range children {
+		forObjs := generators[child]
+		if len(forObjs) < 2 {
+			continue
+		}
+		owners := make([]string, 0, len(forObjs))
+		for _, o := range forObjs {
+			owners = append(owners, fmt.Sprintf("%s %s", o.GetKind(), o.GetName()))
+		}
+		for _, o := range forObjs {
+			violations = append(violations, Violation{
+				Object:   o,
+				Message:  fmt.Sprintf("child %s is generated by %s", child, strings.Join(owners, " and ")),
+				Severity: fn.Error,
+			})
+		}
+	}
+	return violations, nil
+}
+
+// childRef returns the ref of the child with the kind owned by the function
+func childRef(f fnmeta.Description, kind, name string) (readiness.Ref, error) {
+	for _, o := range f.Owns {
+		if o.Kind == kind {
+			return readiness.ParseConditionType(fmt.Sprintf("%s.%s.%s", o.APIVersion, o.Kind, name))
+		}
+	}
+	return readiness.Ref{}, fmt.Errorf("function %s does not own %s", f.Name, kind)
+}
+
+// interfaceChildren returns the children of an interface named by the same
+// rules as interfacefn
+func interfaceChildren(f fnmeta.Description, forObj *fn.KubeObject, objs fn.KubeObjects) ([]readiness.Ref, error) {
+	var capacity *fn.KubeObject
+	for _, o := range objs.Where(fn.IsGroupVersionKind(capacityGVK)) {
+		capacity = o
+	}
+	names, err := children.Interface(pkgcontext.New(objs), forObj, capacity)
+	if err != nil {
+		return nil, err
+	}
+	return childRefs(f, names)
+}
+
+// dnnChildren returns the children of a data network named by the same rules
+// as dnnfn
+func dnnChildren(f fnmeta.Description, forObj *fn.KubeObject, objs fn.KubeObjects) ([]readiness.Ref, error) {
+	names, err := children.DataNetwork(pkgcontext.New(objs), forObj)
+	if err != nil {
+		return nil, err
+	}
+	return childRefs(f, names)
+}
+
+func childRefs(f fnmeta.Description, names children.Names) ([]readiness.Ref, error) {
+	refs := []readiness.Ref{}
+	for kind, kindNames := range names {
+		for _, name := range kindNames {
+			ref, err := childRef(f, kind, name)
+			if err != nil {
+				return nil, err
+			}
+			refs = append(refs, ref)
+		}
+	}
+	return refs, nil
+}
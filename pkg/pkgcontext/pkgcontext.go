@@ -0,0 +1,121 @@
+/*
+Copyright 2023 The Nephio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkgcontext
+
+import (
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt-functions-sdk/go/fn"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ConfigMapName is the name of the ConfigMap kpt uses to expose
+	// the package context to the functions in the pipeline
+	ConfigMapName = "kptfile.kpt.dev"
+
+	// standard kubernetes application labels
+	LabelPrefix    = "app.kubernetes.io/"
+	LabelName      = LabelPrefix + "name"
+	LabelInstance  = LabelPrefix + "instance"
+	LabelPartOf    = LabelPrefix + "part-of"
+	LabelManagedBy = LabelPrefix + "managed-by"
+)
+
+// PackageContext contains the package information provided through
+// the kptfile.kpt.dev ConfigMap
+type PackageContext struct {
+	Name      string
+	Namespace string
+}
+
+// New returns the PackageContext from the kptfile.kpt.dev ConfigMap in the
+// list of objects. An empty PackageContext is returned if the ConfigMap
+// is not present in the package.
+func New(objs fn.KubeObjects) *PackageContext {
+	r := &PackageContext{}
+	for _, o := range objs.Where(fn.IsGVK("", "v1", "ConfigMap")) {
+		if o.GetName() != ConfigMapName {
+			continue
+		}
+		if name, ok, err := o.NestedString("data", "name"); err == nil && ok {
+			r.Name = name
+		}
+		if namespace, ok, err := o.NestedString("data", "namespace"); err == nil && ok {
+			r.Namespace = namespace
+		}
+	}
+	return r
+}
+
+// GetNamespace returns the namespace of the for object if set, otherwise the
+// namespace from the package context
+func (r *PackageContext) GetNamespace(forObj *fn.KubeObject) string {
+	if forObj != nil && forObj.GetNamespace() != "" {
+		return forObj.GetNamespace()
+	}
+	if r == nil {
+		return ""
+	}
+	return r.Namespace
+}
+
+// GetLabels returns the labels a child resource inherits: the standard
+// app.kubernetes.io labels of the for object, complemented with the package
+// name and the function managing the child resource
+func (r *PackageContext) GetLabels(forObj *fn.KubeObject, managedBy string) map[string]string {
+	labels := map[string]string{}
+	if forObj != nil {
+		for k, v := range forObj.GetLabels() {
+			if strings.HasPrefix(k, LabelPrefix) {
+				labels[k] = v
+			}
+		}
+		if _, ok := labels[LabelInstance]; !ok {
+			labels[LabelInstance] = forObj.GetName()
+		}
+	}
+	if r != nil && r.Name != "" {
+		if _, ok := labels[LabelPartOf]; !ok {
+			labels[LabelPartOf] = r.Name
+		}
+	}
+	if managedBy != "" {
+		labels[LabelManagedBy] = managedBy
+	}
+	return labels
+}
+
+// ChildName returns the name of the children of the for object with the
+// given name. The name is prefixed with the package name such that the
+// children of several NF packages applied to one namespace do not collide.
+func (r *PackageContext) ChildName(name string) string {
+	if r == nil || r.Name == "" {
+		return name
+	}
+	return r.Name + "-" + name
+}
+
+// BuildObjectMeta returns the object meta of a child resource with the given
+// name, see ChildName, inheriting the namespace and labels from the for object and package context
+func (r *PackageContext) BuildObjectMeta(forObj *fn.KubeObject, name, managedBy string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:      name,
+		Namespace: r.GetNamespace(forObj),
+		Labels:    r.GetLabels(forObj, managedBy),
+	}
+}
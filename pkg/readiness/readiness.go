@@ -0,0 +1,247 @@
+/*
+Copyright 2023 The Nephio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package readiness
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt-functions-sdk/go/fn"
+	kptfilev1 "github.com/henderiw-nephio/pkg-examples/pkg/kptfile/v1"
+)
+
+const (
+	// ReadyConditionType is the type of the top-level readiness condition
+	ReadyConditionType = "Ready"
+)
+
+// Ref identifies the resource a condition refers to. The condition type has
+// the format <group>/<version>.<kind>.<name>, or <version>.<kind>.<name> for
+// the core group.
+type Ref struct {
+	Group   string
+	Version string
+	Kind    string
+	Name    string
+}
+
+// ParseConditionType returns the Ref the condition type refers to
+func ParseConditionType(s string) (Ref, error) {
+	group := ""
+	rest := s
+	if i := strings.Index(s, "/"); i >= 0 {
+		group, rest = s[:i], s[i+1:]
+	}
+	split := strings.SplitN(rest, ".", 3)
+	if len(split) != 3 || split[0] == "" || split[1] == "" || split[2] == "" {
+		return Ref{}, fmt.Errorf("invalid condition type: %q", s)
+	}
+	return Ref{Group: group, Version: split[0], Kind: split[1], Name: split[2]}, nil
+}
+
+// NewRef returns the Ref of the object
+func NewRef(o *fn.KubeObject) Ref {
+	gvk := o.GroupVersionKind()
+	return Ref{Group: gvk.Group, Version: gvk.Version, Kind: gvk.Kind, Name: o.GetName()}
+}
+
+// APIVersion returns the apiVersion of the Ref
+func (r Ref) APIVersion() string {
+	if r.Group == "" {
+		return r.Version
+	}
+	return r.Group + "/" + r.Version
+}
+
+// String returns the condition type representation of the Ref
+func (r Ref) String() string {
+	return fmt.Sprintf("%s.%s.%s", r.APIVersion(), r.Kind, r.Name)
+}
+
+// Node is a condition in the readiness tree, the children are the conditions
+// of the resources owned by the resource of the node
+type Node struct {
+	Ref       Ref
+	Condition kptfilev1.Condition
+	Children  []*Node
+}
+
+// IsReady returns true if the condition of the node and of all its children
+// are true
+func (r *Node) IsReady() bool {
+	if r.Condition.Status != kptfilev1.ConditionTrue {
+		return false
+	}
+	for _, child := range r.Children {
+		if !child.IsReady() {
+			return false
+		}
+	}
+	return true
+}
+
+// Summary is the readiness of a package
+type Summary struct {
+	Ready    bool
+	Total    int
+	NotReady int
+	Tree     []*Node
+	// Other are the conditions whose type does not refer to a resource, they
+	// count for the readiness but are not part of the tree
+	Other []kptfilev1.Condition
+}
+
+// Summarize builds the readiness tree from the owners in the reason of the
+// conditions, at any depth, and returns the readiness summary. Conditions
+// whose owner has no condition of its own are grouped under a node for the
+// owner with an unknown status, conditions whose type does not refer to a
+// resource are reported separately.
+func Summarize(conditions []kptfilev1.Condition) *Summary {
+	summary := &Summary{Ready: true}
+	nodes := map[string]*Node{}
+	types := []string{}
+	for _, c := range conditions {
+		if c.Type == ReadyConditionType {
+			continue
+		}
+		summary.Total++
+		if c.Status != kptfilev1.ConditionTrue {
+			summary.Ready = false
+			summary.NotReady++
+		}
+		ref, err := ParseConditionType(c.Type)
+		if err != nil {
+			summary.Other = append(summary.Other, c)
+			continue
+		}
+		nodes[c.Type] = &Node{Ref: ref, Condition: c}
+		types = append(types, c.Type)
+	}
+
+	// the owners are resolved in the order of the condition types such that
+	// the tree does not depend on the order of the conditions
+	sort.Strings(types)
+	parents := map[*Node]*Node{}
+	for _, t := range types {
+		node := nodes[t]
+		owner := node.Condition.Reason
+		if owner == "" {
+			continue
+		}
+		parent, ok := nodes[owner]
+		if !ok {
+			ref, err := ParseConditionType(owner)
+			if err != nil {
+				// the reason is not an owner reference, hence the condition
+				// is a top-level condition
+				continue
+			}
+			parent = &Node{Ref: ref, Condition: kptfilev1.Condition{Type: owner, Status: kptfilev1.ConditionUnknown}}
+			nodes[owner] = parent
+		}
+		if isAncestor(parents, node, parent) {
+			// conditions owning each other are kept at the top-level
+			continue
+		}
+		parent.Children = append(parent.Children, node)
+		parents[node] = parent
+	}
+
+	for _, node := range nodes {
+		sortNodes(node.Children)
+		if _, ok := parents[node]; !ok {
+			summary.Tree = append(summary.Tree, node)
+		}
+	}
+	sortNodes(summary.Tree)
+	sort.Slice(summary.Other, func(i, j int) bool {
+		return summary.Other[i].Type < summary.Other[j].Type
+	})
+	return summary
+}
+
+// isAncestor returns true if the node is the other node or one of its owners
+func isAncestor(parents map[*Node]*Node, node, other *Node) bool {
+	for n := other; n != nil; n = parents[n] {
+		if n == node {
+			return true
+		}
+	}
+	return false
+}
+
+func sortNodes(nodes []*Node) {
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Condition.Type < nodes[j].Condition.Type
+	})
+}
+
+// String returns the readiness tree and the overall verdict
+func (r *Summary) String() string {
+	var sb strings.Builder
+	var write func(nodes []*Node, indent string)
+	write = func(nodes []*Node, indent string) {
+		for _, n := range nodes {
+			fmt.Fprintf(&sb, "%s%s %s (%s): %s", indent, n.Ref.Kind, n.Ref.Name, n.Ref.APIVersion(), n.Condition.Status)
+			if n.Condition.Message != "" {
+				fmt.Fprintf(&sb, " - %s", n.Condition.Message)
+			}
+			sb.WriteString("\n")
+			write(n.Children, indent+"  ")
+		}
+	}
+	write(r.Tree, "")
+	if len(r.Other) > 0 {
+		sb.WriteString("other conditions:\n")
+		for _, c := range r.Other {
+			fmt.Fprintf(&sb, "  %s: %s", c.Type, c.Status)
+			if c.Message != "" {
+				fmt.Fprintf(&sb, " - %s", c.Message)
+			}
+			sb.WriteString("\n")
+		}
+	}
+	fmt.Fprintf(&sb, "%s: %s - %s\n", ReadyConditionType, r.Status(), r.Message())
+	return sb.String()
+}
+
+// Status returns the condition status of the overall verdict
+func (r *Summary) Status() kptfilev1.ConditionStatus {
+	if r.Ready {
+		return kptfilev1.ConditionTrue
+	}
+	return kptfilev1.ConditionFalse
+}
+
+// Message returns the message of the top-level readiness condition
+func (r *Summary) Message() string {
+	if r.Ready {
+		return fmt.Sprintf("all %d conditions are true", r.Total)
+	}
+	return fmt.Sprintf("%d of %d conditions are not true", r.NotReady, r.Total)
+}
+
+// SetReadyCondition sets the top-level readiness condition in the Kptfile
+// based on the summary, the other conditions are kept as is
+func SetReadyCondition(kptfile *kptfilev1.Kptfile, summary *Summary) error {
+	return kptfile.SetConditions(kptfilev1.Condition{
+		Type:    ReadyConditionType,
+		Status:  summary.Status(),
+		Message: summary.Message(),
+	})
+}
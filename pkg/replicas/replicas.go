@@ -0,0 +1,140 @@
+/*
+Copyright 2023 The Nephio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replicas
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt-functions-sdk/go/fn"
+	nadlibv1 "github.com/henderiw-nephio/pkg-examples/pkg/nad/v1"
+	"github.com/henderiw-nephio/pkg-examples/pkg/readiness"
+	"github.com/nephio-project/nephio/krm-functions/lib/condkptsdk"
+)
+
+const (
+	// Annotation defines the amount of replicas of the NF. It is set on the
+	// Capacity of the package and can be overridden per Interface.
+	Annotation = "nephio.org/replicas"
+
+	// defaultReplicas is used when no replica annotation is present
+	defaultReplicas = 1
+)
+
+var (
+	// StatusField is the path in the Interface status that lists the
+	// ip allocation status of every replica
+	StatusField = []string{"status", "replicaIPAllocationStatus"}
+)
+
+// Get returns the replicas from the annotation of the first object that has
+// it set, such that more specific objects can be passed first. The default
+// of a single replica is returned if none of the objects has the annotation.
+func Get(objs ...*fn.KubeObject) (int, error) {
+	for _, o := range objs {
+		if o == nil {
+			continue
+		}
+		s := o.GetAnnotation(Annotation)
+		if s == "" {
+			continue
+		}
+		replicas, err := strconv.Atoi(s)
+		if err != nil || replicas < 1 {
+			return 0, fmt.Errorf("invalid annotation %s: %q on %s %q, expected a positive integer", Annotation, s, o.GetKind(), o.GetName())
+		}
+		return replicas, nil
+	}
+	return defaultReplicas, nil
+}
+
+// AllocationName returns the name of the allocation of the replica with the
+// given index. The first replica keeps the name of the parent such that
+// scaling out does not rename, and hence reallocate, its allocation.
+func AllocationName(name string, index int) string {
+	if index == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s-%d", name, index)
+}
+
+// NadNames returns the names of the nads of the interface whose children are
+// named after name. The replicas share a single nad if the ipam type of the
+// interface hands out the addresses, otherwise every replica gets a nad of
+// its own pinning its address.
+func NadNames(itfce *fn.KubeObject, name string, replicas int) []string {
+	if replicas <= 1 || nadlibv1.IsSharedIpamType(itfce.GetAnnotation(nadlibv1.IpamTypeAnnotation)) {
+		return []string{name}
+	}
+	names := make([]string, 0, replicas)
+	for i := 0; i < replicas; i++ {
+		names = append(names, AllocationName(name, i))
+	}
+	return names
+}
+
+// GetIndex returns the replica index of the allocation and true if the
+// allocation is named after a replica of the parent with the given name
+func GetIndex(allocName, name string) (int, bool) {
+	if allocName == name {
+		return 0, true
+	}
+	if !strings.HasPrefix(allocName, name+"-") {
+		return 0, false
+	}
+	index, err := strconv.Atoi(strings.TrimPrefix(allocName, name+"-"))
+	if err != nil || index < 1 {
+		return 0, false
+	}
+	return index, true
+}
+
+// IsOwnedBy returns true if the owner annotation of the object refers to the
+// owner, the name alone is ambiguous as the children of an interface n3-1
+// look like the second replica of the interface n3
+func IsOwnedBy(o, owner *fn.KubeObject) bool {
+	return o.GetAnnotation(condkptsdk.SpecializerOwner) == readiness.NewRef(owner).String()
+}
+
+// Filter returns the allocations owned by the parent and named after one of
+// its replicas, sorted by replica index. The name is the name the children
+// of the parent are derived from, see ChildName of the package context.
+func Filter(objs fn.KubeObjects, owner *fn.KubeObject, name string) fn.KubeObjects {
+	type indexedObj struct {
+		index int
+		o     *fn.KubeObject
+	}
+	indexed := []indexedObj{}
+	for _, o := range objs {
+		if !IsOwnedBy(o, owner) {
+			continue
+		}
+		if index, ok := GetIndex(o.GetName(), name); ok {
+			indexed = append(indexed, indexedObj{index: index, o: o})
+		}
+	}
+	sort.SliceStable(indexed, func(i, j int) bool {
+		return indexed[i].index < indexed[j].index
+	})
+	allocs := make(fn.KubeObjects, 0, len(indexed))
+	for _, x := range indexed {
+		allocs = append(allocs, x.o)
+	}
+	return allocs
+}
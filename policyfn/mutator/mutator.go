@@ -0,0 +1,185 @@
+package mutator
+
+import (
+	"reflect"
+
+	"github.com/GoogleContainerTools/kpt-functions-sdk/go/fn"
+	"github.com/henderiw-nephio/pkg-examples/pkg/fnmeta"
+	"github.com/henderiw-nephio/pkg-examples/pkg/localconfig"
+	mnpv1beta1 "github.com/henderiw-nephio/pkg-examples/pkg/multinetworkpolicy"
+	"github.com/henderiw-nephio/pkg-examples/pkg/pkgcontext"
+	"github.com/henderiw-nephio/pkg-examples/pkg/replicas"
+	nadv1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	defaultPODNetwork = "defaultPODNetwork"
+	fnName            = "policy-fn"
+)
+
+var (
+	interfaceGVK   = schema.GroupVersionKind{Group: "req.nephio.org", Version: "v1alpha1", Kind: "Interface"}
+	dataNetworkGVK = schema.GroupVersionKind{Group: "req.nephio.org", Version: "v1alpha1", Kind: "DataNetwork"}
+	nadGVK         = nadv1.SchemeGroupVersion.WithKind(reflect.TypeOf(nadv1.NetworkAttachmentDefinition{}).Name())
+)
+
+// Describe returns the description of the function
+func Describe() fnmeta.Description {
+	d := fnmeta.Description{
+		Name: fnName,
+		For:  fnmeta.NewResource(mnpv1beta1.GroupVersionKind),
+		Watch: []fnmeta.Resource{
+			fnmeta.NewResource(dataNetworkGVK),
+			fnmeta.NewResource(interfaceGVK),
+			fnmeta.NewResource(nadGVK),
+		},
+	}
+	d.Sort()
+	return d
+}
+
+// Run generates a MultiNetworkPolicy per nad in the package that restricts
+// the traffic on the secondary network to the network of the interface and
+// the data network pools reachable through it
+func Run(rl *fn.ResourceList) (bool, error) {
+	pkgCtx := pkgcontext.New(rl.Items)
+	pools, err := getPools(rl.Items)
+	if err != nil {
+		rl.Results.ErrorE(err)
+		return false, nil
+	}
+
+	policies := map[string]struct{}{}
+	for _, itfce := range rl.Items.Where(fn.IsGroupVersionKind(interfaceGVK)) {
+		// the nads of all replicas of the interface, a nad of another
+		// interface named like a replica is not selected
+		nads := replicas.Filter(rl.Items.Where(fn.IsGroupVersionKind(nadGVK)), itfce, pkgCtx.ChildName(itfce.GetName()))
+		if len(nads) == 0 {
+			continue
+		}
+		ni, _, err := itfce.NestedString("spec", "networkInstance", "name")
+		if err != nil {
+			rl.Results = append(rl.Results, fn.ErrorConfigObjectResult(err, itfce))
+			return false, nil
+		}
+		if ni == defaultPODNetwork {
+			continue
+		}
+		// traffic is allowed within the network of the interface and to the
+		// data network pools reachable through the network instance
+		prefixes, err := getInterfacePrefixes(itfce)
+		if err != nil {
+			rl.Results = append(rl.Results, fn.ErrorConfigObjectResult(err, itfce))
+			return false, nil
+		}
+		prefixes = append(prefixes, pools[ni]...)
+		networks, err := mnpv1beta1.GetNetworks(prefixes...)
+		if err != nil {
+			rl.Results = append(rl.Results, fn.ErrorConfigObjectResult(err, itfce))
+			return false, nil
+		}
+		// the prefixes are only known once the allocations are done
+		if len(networks) == 0 {
+			rl.Results = append(rl.Results, fn.ConfigObjectResult("no allocated prefixes found, policy not generated", itfce, fn.Info))
+			continue
+		}
+
+		// a policy per nad such that the secondary network of every replica
+		// is restricted
+		for _, nad := range nads {
+			meta := pkgCtx.BuildObjectMeta(itfce, nad.GetName(), fnName)
+			// the policy applies to the pods in its own namespace
+			if nad.GetNamespace() != "" {
+				meta.Namespace = nad.GetNamespace()
+			}
+			policy, err := fn.NewFromTypedObject(mnpv1beta1.BuildMultiNetworkPolicy(
+				meta,
+				[]string{getNadRef(nad)},
+				networks,
+			))
+			if err != nil {
+				rl.Results = append(rl.Results, fn.ErrorConfigObjectResult(err, itfce))
+				return false, nil
+			}
+			if err := localconfig.Set(policy, false); err != nil {
+				rl.Results.ErrorE(err)
+				return false, nil
+			}
+			if err := rl.UpsertObjectToItems(policy, nil, true); err != nil {
+				rl.Results.ErrorE(err)
+				return false, nil
+			}
+			policies[policy.GetName()] = struct{}{}
+		}
+	}
+
+	// remove the policies of nads that no longer exist
+	items := fn.KubeObjects{}
+	for _, o := range rl.Items {
+		if o.GroupVersionKind() == mnpv1beta1.GroupVersionKind && o.GetLabel(pkgcontext.LabelManagedBy) == fnName {
+			if _, ok := policies[o.GetName()]; !ok {
+				continue
+			}
+		}
+		items = append(items, o)
+	}
+	rl.Items = items
+	return true, nil
+}
+
+// getPools returns the prefixes of the data network pools per network instance
+func getPools(objs fn.KubeObjects) (map[string][]string, error) {
+	pools := map[string][]string{}
+	for _, dnn := range objs.Where(fn.IsGroupVersionKind(dataNetworkGVK)) {
+		ni, _, err := dnn.NestedString("spec", "networkInstance", "name")
+		if err != nil {
+			return nil, err
+		}
+		poolStatus, _, err := dnn.NestedSlice("status", "pools")
+		if err != nil {
+			return nil, err
+		}
+		for _, pool := range poolStatus {
+			prefix, _, err := pool.NestedString("ipAllocation", "prefix")
+			if err != nil {
+				return nil, err
+			}
+			if prefix != "" {
+				pools[ni] = append(pools[ni], prefix)
+			}
+		}
+	}
+	return pools, nil
+}
+
+// getInterfacePrefixes returns the prefixes allocated to the interface and
+// its replicas
+func getInterfacePrefixes(itfce *fn.KubeObject) ([]string, error) {
+	prefixes := []string{}
+	prefix, _, err := itfce.NestedString("status", "ipAllocationStatus", "prefix")
+	if err != nil {
+		return nil, err
+	}
+	prefixes = append(prefixes, prefix)
+	replicaStatus, _, err := itfce.NestedSlice(replicas.StatusField...)
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range replicaStatus {
+		prefix, _, err := status.NestedString("prefix")
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes, nil
+}
+
+// getNadRef returns the reference of the nad in the policy-for annotation
+func getNadRef(nad *fn.KubeObject) string {
+	if nad.GetNamespace() == "" {
+		return nad.GetName()
+	}
+	return nad.GetNamespace() + "/" + nad.GetName()
+}
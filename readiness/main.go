@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	kptfilev1 "github.com/henderiw-nephio/pkg-examples/pkg/kptfile/v1"
+	"github.com/henderiw-nephio/pkg-examples/pkg/readiness"
+)
+
+// readiness prints the readiness tree of a package from the conditions in
+// its Kptfile and exits non-zero if the package is not ready.
+//
+//	readiness -package data/pkg-upf
+//	readiness -package data/pkg-upf -set-condition
+func main() {
+	pkg := flag.String("package", "", "directory of the package")
+	setCondition := flag.Bool("set-condition", false, "set the top-level Ready condition in the Kptfile")
+	flag.Parse()
+
+	if *pkg == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	path := filepath.Join(*pkg, kptfilev1.KptfileName)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	kptfile, err := kptfilev1.NewFromYAML(b)
+	if err != nil {
+		log.Fatal(err)
+	}
+	conditions, err := kptfile.GetConditions()
+	if err != nil {
+		log.Fatal(err)
+	}
+	summary := readiness.Summarize(conditions)
+	fmt.Print(summary.String())
+
+	if *setCondition {
+		if err := readiness.SetReadyCondition(kptfile, summary); err != nil {
+			log.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(kptfile.String()), 0644); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if !summary.Ready {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,341 @@
+package mutator
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/GoogleContainerTools/kpt-functions-sdk/go/fn"
+	"github.com/henderiw-nephio/pkg-examples/pkg/fnmeta"
+	"github.com/henderiw-nephio/pkg-examples/pkg/localconfig"
+	"github.com/henderiw-nephio/pkg-examples/pkg/macalloc"
+	nadlibv1 "github.com/henderiw-nephio/pkg-examples/pkg/nad/v1"
+	"github.com/henderiw-nephio/pkg-examples/pkg/pkgcontext"
+	"github.com/henderiw-nephio/pkg-examples/pkg/replicas"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	fnName = "render-fn"
+	// bitsPerSecondSuffix is the suffix of the capacity throughput keys with
+	// the rate in bits per second
+	bitsPerSecondSuffix = "BitsPerSecond"
+	// TemplateAnnotation marks a ConfigMap as a template, the value is the
+	// name of the ConfigMap rendered from it
+	TemplateAnnotation = localconfig.TemplateAnnotation
+)
+
+var (
+	interfaceGVK   = schema.GroupVersionKind{Group: "req.nephio.org", Version: "v1alpha1", Kind: "Interface"}
+	dataNetworkGVK = schema.GroupVersionKind{Group: "req.nephio.org", Version: "v1alpha1", Kind: "DataNetwork"}
+	capacityGVK    = schema.GroupVersionKind{Group: "req.nephio.org", Version: "v1alpha1", Kind: "Capacity"}
+	configMapGVK   = schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+)
+
+// Describe returns the description of the function
+func Describe() fnmeta.Description {
+	d := fnmeta.Description{
+		Name: fnName,
+		For:  fnmeta.NewResource(configMapGVK),
+		Watch: []fnmeta.Resource{
+			fnmeta.NewResource(capacityGVK),
+			fnmeta.NewResource(dataNetworkGVK),
+			fnmeta.NewResource(interfaceGVK),
+		},
+	}
+	d.Sort()
+	return d
+}
+
+// Run renders the template ConfigMaps in the package with the resolved
+// requirements. Only the fields that are resolved are exposed to the
+// templates, such that a template referring to a requirement that is not
+// ready fails with a result pointing at the missing field.
+func Run(rl *fn.ResourceList) (bool, error) {
+	pkgCtx := pkgcontext.New(rl.Items)
+	data, err := buildTemplateData(rl.Items, pkgCtx)
+	if err != nil {
+		rl.Results.ErrorE(err)
+		return false, nil
+	}
+
+	for _, o := range rl.Items.Where(fn.IsGroupVersionKind(configMapGVK)) {
+		name := o.GetAnnotation(TemplateAnnotation)
+		if name == "" {
+			continue
+		}
+		// the template itself must never be applied to the cluster
+		if err := localconfig.Set(o, true); err != nil {
+			rl.Results.ErrorE(err)
+			return false, nil
+		}
+		cm, results := render(o, name, data, pkgCtx)
+		if len(results) > 0 {
+			rl.Results = append(rl.Results, results...)
+			continue
+		}
+		if err := rl.UpsertObjectToItems(cm, nil, true); err != nil {
+			rl.Results.ErrorE(err)
+			return false, nil
+		}
+	}
+	if rl.Results.ExitCode() != 0 {
+		return false, nil
+	}
+	return true, nil
+}
+
+// render returns the ConfigMap rendered from the template ConfigMap or the
+// results of the template keys that cannot be rendered
+func render(tmpl *fn.KubeObject, name string, data map[string]any, pkgCtx *pkgcontext.PackageContext) (*fn.KubeObject, fn.Results) {
+	results := fn.Results{}
+	tmplData, _, err := tmpl.NestedStringMap("data")
+	if err != nil {
+		return nil, append(results, fn.ErrorConfigObjectResult(err, tmpl))
+	}
+	keys := make([]string, 0, len(tmplData))
+	for k := range tmplData {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	rendered := map[string]string{}
+	for _, k := range keys {
+		t, err := template.New(k).Option("missingkey=error").Parse(tmplData[k])
+		if err != nil {
+			results = append(results, fieldResult(err, tmpl, k))
+			continue
+		}
+		var buf bytes.Buffer
+		if err := t.Execute(&buf, data); err != nil {
+			results = append(results, fieldResult(fmt.Errorf("requirement not ready: %v", err), tmpl, k))
+			continue
+		}
+		rendered[k] = buf.String()
+	}
+	if len(results) > 0 {
+		return nil, results
+	}
+
+	cm := fn.NewEmptyKubeObject()
+	for _, set := range []func() error{
+		func() error { return cm.SetAPIVersion("v1") },
+		func() error { return cm.SetKind("ConfigMap") },
+		func() error { return cm.SetName(name) },
+		func() error {
+			namespace := pkgCtx.GetNamespace(tmpl)
+			if namespace == "" {
+				return nil
+			}
+			return cm.SetNamespace(namespace)
+		},
+		func() error { return cm.SetNestedStringMap(pkgCtx.GetLabels(tmpl, fnName), "metadata", "labels") },
+		func() error { return localconfig.Set(cm, false) },
+		func() error { return cm.SetNestedStringMap(rendered, "data") },
+	} {
+		if err := set(); err != nil {
+			return nil, append(results, fn.ErrorConfigObjectResult(err, tmpl))
+		}
+	}
+	return cm, nil
+}
+
+// fieldResult returns an error result pointing at the template key
+func fieldResult(err error, o *fn.KubeObject, key string) *fn.Result {
+	r := fn.ErrorConfigObjectResult(err, o)
+	r.Field = &fn.Field{Path: strings.Join([]string{"data", key}, ".")}
+	return r
+}
+
+// buildTemplateData returns the data exposed to the templates:
+//
+//	.package.name, .package.namespace
+//	.interfaces.<name>.{networkInstance,cniType,attachmentType,prefix,address,gateway,vlanID,macAddress,replicas}
+//	.dataNetworks.<name>.{networkInstance,pools.<name>.prefix}
+//	.capacity.{maxUplinkThroughput,maxDownlinkThroughput,maxUplinkThroughputBitsPerSecond,maxDownlinkThroughputBitsPerSecond,maxSessions,maxSubscribers,maxNFConnections}
+func buildTemplateData(objs fn.KubeObjects, pkgCtx *pkgcontext.PackageContext) (map[string]any, error) {
+	pkg := map[string]any{}
+	setString(pkg, "name", pkgCtx.Name)
+	setString(pkg, "namespace", pkgCtx.Namespace)
+
+	interfaces := map[string]any{}
+	for _, o := range objs.Where(fn.IsGroupVersionKind(interfaceGVK)) {
+		itfce, err := getInterfaceData(o)
+		if err != nil {
+			return nil, err
+		}
+		interfaces[o.GetName()] = itfce
+	}
+
+	dataNetworks := map[string]any{}
+	for _, o := range objs.Where(fn.IsGroupVersionKind(dataNetworkGVK)) {
+		dnn, err := getDataNetworkData(o)
+		if err != nil {
+			return nil, err
+		}
+		dataNetworks[o.GetName()] = dnn
+	}
+
+	capacity := map[string]any{}
+	for _, o := range objs.Where(fn.IsGroupVersionKind(capacityGVK)) {
+		var err error
+		capacity, err = getCapacityData(o)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return map[string]any{
+		"package":      pkg,
+		"interfaces":   interfaces,
+		"dataNetworks": dataNetworks,
+		"capacity":     capacity,
+	}, nil
+}
+
+func getInterfaceData(o *fn.KubeObject) (map[string]any, error) {
+	itfce := map[string]any{"name": o.GetName()}
+	for k, fields := range map[string][]string{
+		"networkInstance": {"spec", "networkInstance", "name"},
+		"cniType":         {"spec", "cniType"},
+		"attachmentType":  {"spec", "attachmentType"},
+		"gateway":         {"status", "ipAllocationStatus", "gateway"},
+	} {
+		if err := setNestedString(itfce, k, o, fields...); err != nil {
+			return nil, err
+		}
+	}
+	prefix, _, err := o.NestedString("status", "ipAllocationStatus", "prefix")
+	if err != nil {
+		return nil, err
+	}
+	setPrefix(itfce, prefix)
+	setString(itfce, "macAddress", macalloc.GetReplica(o, 0))
+	vlanID, ok, err := o.NestedInt("status", "vlanAllocationStatus", "vlanID")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		itfce["vlanID"] = vlanID
+	}
+
+	replicaStatus, _, err := o.NestedSlice(replicas.StatusField...)
+	if err != nil {
+		return nil, err
+	}
+	if len(replicaStatus) > 0 {
+		replicaData := make([]map[string]any, 0, len(replicaStatus))
+		for i, status := range replicaStatus {
+			replica := map[string]any{}
+			prefix, _, err := status.NestedString("prefix")
+			if err != nil {
+				return nil, err
+			}
+			setPrefix(replica, prefix)
+			gateway, _, err := status.NestedString("gateway")
+			if err != nil {
+				return nil, err
+			}
+			setString(replica, "gateway", gateway)
+			setString(replica, "macAddress", macalloc.GetReplica(o, i))
+			replicaData = append(replicaData, replica)
+		}
+		itfce["replicas"] = replicaData
+	}
+	return itfce, nil
+}
+
+func getDataNetworkData(o *fn.KubeObject) (map[string]any, error) {
+	dnn := map[string]any{"name": o.GetName()}
+	if err := setNestedString(dnn, "networkInstance", o, "spec", "networkInstance", "name"); err != nil {
+		return nil, err
+	}
+	poolStatus, _, err := o.NestedSlice("status", "pools")
+	if err != nil {
+		return nil, err
+	}
+	pools := map[string]any{}
+	for _, status := range poolStatus {
+		name, _, err := status.NestedString("name")
+		if err != nil {
+			return nil, err
+		}
+		pool := map[string]any{"name": name}
+		if err := setNestedString(pool, "prefix", status, "ipAllocation", "prefix"); err != nil {
+			return nil, err
+		}
+		pools[name] = pool
+	}
+	dnn["pools"] = pools
+	return dnn, nil
+}
+
+func getCapacityData(o *fn.KubeObject) (map[string]any, error) {
+	capacity := map[string]any{}
+	for _, field := range []string{"maxUplinkThroughput", "maxDownlinkThroughput"} {
+		// the throughput is either a quantity such as 10G or a number, it is
+		// exposed as is and as a number in bits per second
+		s, ok, err := o.NestedString("spec", field)
+		if err != nil {
+			rate, _, err := o.NestedInt64("spec", field)
+			if err != nil {
+				return nil, err
+			}
+			capacity[field] = rate
+			capacity[field+bitsPerSecondSuffix] = rate
+			continue
+		}
+		if !ok {
+			continue
+		}
+		rate, err := nadlibv1.ParseRate(s)
+		if err != nil {
+			return nil, err
+		}
+		capacity[field] = s
+		capacity[field+bitsPerSecondSuffix] = rate
+	}
+	for _, field := range []string{"maxSessions", "maxSubscribers", "maxNFConnections"} {
+		i, ok, err := o.NestedInt("spec", field)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			capacity[field] = i
+		}
+	}
+	return capacity, nil
+}
+
+// setPrefix sets the prefix and the address without prefix length
+func setPrefix(m map[string]any, prefix string) {
+	setString(m, "prefix", prefix)
+	address, _, _ := strings.Cut(prefix, "/")
+	setString(m, "address", address)
+}
+
+// nestedStringGetter is implemented by KubeObjects and SubObjects
+type nestedStringGetter interface {
+	NestedString(fields ...string) (string, bool, error)
+}
+
+// setNestedString sets the key to the string value of the field of the
+// object, the key is not set when the field is empty
+func setNestedString(m map[string]any, k string, o nestedStringGetter, fields ...string) error {
+	s, _, err := o.NestedString(fields...)
+	if err != nil {
+		return err
+	}
+	setString(m, k, s)
+	return nil
+}
+
+// setString sets the key if the value is not empty, such that templates
+// referring to it fail with a missing key
+func setString(m map[string]any, k, v string) {
+	if v != "" {
+		m[k] = v
+	}
+}